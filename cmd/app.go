@@ -12,7 +12,7 @@ func NewApp() *cli.App {
 	app.UsageText = "qdox [global options] command [command options] [arguments...]"
 	app.Author = "Stormcrows"
 	app.Version = "1.0.0"
-	app.Commands = []cli.Command{Search, Serve}
+	app.Commands = []cli.Command{Search, Serve, Cluster, Dupes, Topics, Keywords, Eval, Tune, Shell, Explain}
 
 	return app
 }
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/stormcrows/qdox/pkg/nlp"
+)
+
+// queryCacheKey identifies responses to the same normalised query with the same parameters
+func queryCacheKey(q string, n int, threshold float64, fusion nlp.Fusion, explain bool, asOf time.Time) string {
+	return fmt.Sprintf("%s\x00%d\x00%g\x00%s\x00%g\x00%g\x00%t\x00%s", normaliseQuery(q), n, threshold, fusion.Method, fusion.Semantic, fusion.Lexical, explain,
+		asOf.UTC().Format(time.RFC3339Nano))
+}
+
+// cachedQueryResponse returns response cached for the key, dropping all cached responses once the model changes
+func cachedQueryResponse(key string) (QueryResponse, bool) {
+	if queryCache == nil {
+		return QueryResponse{}, false
+	}
+	queryCache.Sync(currentSearcher().Generation())
+	resp, ok := queryCache.Get(key)
+	if !ok {
+		return QueryResponse{}, false
+	}
+	return resp.(QueryResponse), true
+}
+
+// cacheQueryResponse caches response of the model of given generation, unless the model has changed since
+func cacheQueryResponse(key string, generation uint64, resp QueryResponse) {
+	if queryCache != nil && currentSearcher().Generation() == generation {
+		queryCache.Add(key, resp)
+	}
+}
+
+// CacheHandler responds with JSON statistics of the query cache
+func CacheHandler(w http.ResponseWriter, r *http.Request) {
+	if queryCache == nil {
+		respond(http.StatusNotFound, "query cache is disabled", w)
+		return
+	}
+
+	body, err := json.Marshal(queryCache.Stats())
+	if err != nil {
+		log.Println(fmt.Sprintf("json marshalling error: %s", err))
+		respond(http.StatusInternalServerError, "", w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	respond(http.StatusOK, string(body), w)
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/stormcrows/qdox/pkg/nlp"
+	"github.com/urfave/cli"
+)
+
+// ClusterResult is a single group of documents labelled with its top terms
+type ClusterResult struct {
+	Terms     []string
+	Documents []Result
+}
+
+// ClustersResponse is JSON response to /clusters requests
+type ClustersResponse struct {
+	Clusters []ClusterResult
+}
+
+// Cluster command trains the model and groups documents of the folder into themes
+var Cluster = cli.Command{
+	Name:  "cluster",
+	Usage: "qdox cluster [command options] [folder]",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:        "pattern, P",
+			Usage:       "only parse files matching regular expression",
+			Destination: &pattern,
+			Value:       "\\.txt$",
+		},
+		cli.IntFlag{
+			Name:        "k",
+			Usage:       "maximum number of clusters to create",
+			Destination: &clusters,
+			Value:       3,
+		},
+		cli.IntFlag{
+			Name:        "terms",
+			Usage:       "number of top terms labelling each cluster",
+			Destination: &terms,
+			Value:       5,
+		},
+		cli.StringFlag{
+			Name:        "format, f",
+			Usage:       "output format: table or json",
+			Destination: &format,
+			Value:       "table",
+		},
+	}, loadingFlags...),
+	Action: func(c *cli.Context) {
+		if len(c.Args()) < 1 {
+			fatal(fmt.Errorf("please provide source folder"))
+		}
+
+		patternr = regexp.MustCompile(pattern)
+		fatal(setFilter(c))
+		folder := path.Clean(c.Args().Get(0))
+
+		fatal(loadCorpus(&corpus, folder, filter))
+		fatal(model.Train(&corpus))
+
+		result, err := model.Cluster(clusters, terms)
+		fatal(err)
+
+		switch format {
+		case "json":
+			body, err := json.Marshal(newClustersResponse(result))
+			fatal(err)
+			fmt.Fprintln(c.App.Writer, string(body))
+		case "table":
+			for i, cluster := range result {
+				fmt.Fprintf(c.App.Writer, "#%d %s\n", i+1, strings.Join(cluster.Terms, ", "))
+				for j, v := range cluster.Members {
+					fmt.Fprintf(c.App.Writer, "  %.0f%% %q\n", cluster.Similarities[j]*100.0, corpus.GetPath(v))
+				}
+			}
+		default:
+			fatal(fmt.Errorf("unknown format %q", format))
+		}
+	},
+}
+
+// ClustersHandler groups trained documents and responds with JSON
+func ClustersHandler(w http.ResponseWriter, r *http.Request) {
+	// args
+	var err error
+	args := r.URL.Query()
+
+	k := 3
+	if args.Get("k") != "" {
+		k, err = strconv.Atoi(args.Get("k"))
+		if err != nil || k < 1 {
+			respond(http.StatusBadRequest, "k should be a positive integer", w)
+			return
+		}
+	}
+
+	terms := 5
+	if args.Get("terms") != "" {
+		terms, err = strconv.Atoi(args.Get("terms"))
+		if err != nil || terms < 0 {
+			respond(http.StatusBadRequest, "terms should be a non-negative integer", w)
+			return
+		}
+	}
+
+	log.Println(fmt.Sprintf("ip=%s, clusters k=%d, terms=%d", r.RemoteAddr, k, terms))
+
+	// nlp clustering
+	result, err := model.Cluster(k, terms)
+	if err != nil {
+		log.Println(fmt.Sprintf("clustering error: %s", err))
+		respond(http.StatusInternalServerError, "", w)
+		return
+	}
+
+	// response
+	body, err := json.Marshal(newClustersResponse(result))
+	if err != nil {
+		log.Println(fmt.Sprintf("json marshalling error: %s", err))
+		respond(http.StatusInternalServerError, "", w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	respond(http.StatusOK, string(body), w)
+}
+
+func newClustersResponse(clusters []nlp.Cluster) ClustersResponse {
+	resp := ClustersResponse{make([]ClusterResult, len(clusters))}
+	for i, cluster := range clusters {
+		resp.Clusters[i] = ClusterResult{cluster.Terms, make([]Result, len(cluster.Members))}
+		for j, v := range cluster.Members {
+			resp.Clusters[i].Documents[j] = newResult(v, cluster.Similarities[j])
+		}
+	}
+	return resp
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stormcrows/qdox/pkg/store"
+)
+
+// DocumentResponse is JSON response to /documents/{id} requests, with hash, metadata and LSI vector of the document
+// kept in the store if there is one, which then lists and describes documents
+type DocumentResponse struct {
+	ID         int
+	Name       string
+	Path       string
+	Keywords   []string          `json:",omitempty"`
+	Keyphrases []string          `json:",omitempty"`
+	Hash       string            `json:",omitempty"`
+	Metadata   map[string]string `json:",omitempty"`
+	Vector     []float64         `json:",omitempty"`
+	Content    string            `json:",omitempty"`
+}
+
+// RevisionResponse is a version of the document in /documents/{id}/history responses,
+// written at Since and replaced at Replaced unless it is the current one
+type RevisionResponse struct {
+	Hash     string
+	Since    time.Time
+	Replaced *time.Time        `json:",omitempty"`
+	Metadata map[string]string `json:",omitempty"`
+	Vector   []float64         `json:",omitempty"`
+	Content  string            `json:",omitempty"`
+}
+
+// DocumentsHandler lists trained documents or describes one of them with its keywords, and content if asked to,
+// or lists its revisions under /documents/{id}/history, responding with JSON
+func DocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	// args
+	var err error
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/documents"), "/")
+	history := strings.HasSuffix(id, "/history")
+	id = strings.TrimSuffix(id, "/history")
+	args := r.URL.Query()
+
+	// keywords are retained at training, so there are no more of them to rank
+	k := model.MaxKeywords
+	if args.Get("k") != "" {
+		k, err = strconv.Atoi(args.Get("k"))
+		if err != nil || k < 0 || k > model.MaxKeywords {
+			respond(http.StatusBadRequest, fmt.Sprintf("k should be a non-negative integer up to %d", model.MaxKeywords), w)
+			return
+		}
+	}
+
+	content := false
+	if args.Get("content") != "" {
+		content, err = strconv.ParseBool(args.Get("content"))
+		if err != nil {
+			respond(http.StatusBadRequest, "content should be a boolean", w)
+			return
+		}
+	}
+
+	log.Println(fmt.Sprintf("ip=%s, documents id=%q, k=%d, content=%t, history=%t", r.RemoteAddr, id, k, content, history))
+
+	// response
+	var resp interface{}
+	if id == "" && documentStore != nil {
+		docs, err := storedDocumentResponses()
+		if err != nil {
+			log.Println(fmt.Sprintf("documents error: %s", err))
+			respond(http.StatusInternalServerError, "", w)
+			return
+		}
+		resp = docs
+	} else if id == "" {
+		docs := make([]DocumentResponse, model.Corpus.Len())
+		for i := range docs {
+			docs[i] = newDocumentResponse(i)
+		}
+		resp = docs
+	} else {
+		doc, err := strconv.Atoi(id)
+		if err != nil || doc < 0 || doc >= model.Corpus.Len() {
+			respond(http.StatusNotFound, "", w)
+			return
+		}
+		if history {
+			if documentStore == nil {
+				respond(http.StatusBadRequest, "history needs a store", w)
+				return
+			}
+			revisions, err := documentRevisions(doc, content)
+			if err != nil {
+				log.Println(fmt.Sprintf("document %d history error: %s", doc, err))
+				respond(http.StatusInternalServerError, "", w)
+				return
+			}
+			resp = revisions
+		} else {
+			docResp := newDocumentResponse(doc)
+			docResp.Keywords = keywordTerms(model.Keywords(doc, k))
+			docResp.Keyphrases = keywordTerms(model.Keyphrases(doc, k))
+			found, err := describeDocument(&docResp, doc, content)
+			if err != nil {
+				log.Println(fmt.Sprintf("document %d error: %s", doc, err))
+				respond(http.StatusInternalServerError, "", w)
+				return
+			}
+			if !found {
+				respond(http.StatusNotFound, "", w)
+				return
+			}
+			resp = docResp
+		}
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		log.Println(fmt.Sprintf("json marshalling error: %s", err))
+		respond(http.StatusInternalServerError, "", w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	respond(http.StatusOK, string(body), w)
+}
+
+func newDocumentResponse(doc int) DocumentResponse {
+	result := newResult(doc, 0)
+	return DocumentResponse{ID: doc, Name: result.Name, Path: result.Path}
+}
+
+// describeDocument fills the response with the record of the document kept in the store, along with its content if asked to,
+// telling whether the document is stored. Without a store contents are read from files
+func describeDocument(resp *DocumentResponse, doc int, content bool) (bool, error) {
+	if documentStore == nil {
+		if content {
+			text, err := model.Corpus.Read(doc)
+			resp.Content = text
+			return true, err
+		}
+		return true, nil
+	}
+
+	r, ok, err := documentStore.Get(model.Corpus.GetPath(doc))
+	if err != nil || !ok {
+		return false, err
+	}
+	resp.Hash, resp.Metadata, resp.Vector = r.Hash, r.Metadata, r.Vector
+	if content {
+		resp.Content = r.Content
+	}
+	return true, nil
+}
+
+// storedDocumentResponses lists documents kept in the store in order of their paths, with their hashes and metadata,
+// leaving out those the model is not trained on as they have no id
+func storedDocumentResponses() ([]DocumentResponse, error) {
+	ids := make(map[string]int, model.Corpus.Len())
+	for i, path := range model.Corpus.Paths() {
+		ids[path] = i
+	}
+	docs := make([]DocumentResponse, 0, len(ids))
+	err := documentStore.ForEach(func(r store.Record) error {
+		if id, ok := ids[r.Path]; ok {
+			resp := newDocumentResponse(id)
+			resp.Hash, resp.Metadata = r.Hash, r.Metadata
+			docs = append(docs, resp)
+		}
+		return nil
+	})
+	return docs, err
+}
+
+// documentRevisions lists versions of the document kept in the store, oldest first, along with their contents if asked to
+func documentRevisions(doc int, content bool) ([]RevisionResponse, error) {
+	versions, err := model.Corpus.History(doc)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]RevisionResponse, len(versions))
+	for i, v := range versions {
+		revisions[i] = RevisionResponse{Hash: v.Hash, Since: v.Since, Metadata: v.Metadata, Vector: v.Vector}
+		if !v.Replaced.IsZero() {
+			replaced := v.Replaced
+			revisions[i].Replaced = &replaced
+		}
+		if content {
+			revisions[i].Content = v.Content
+		}
+	}
+	return revisions, nil
+}
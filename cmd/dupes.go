@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/urfave/cli"
+)
+
+// Dupes command loads the corpus and lists documents that are copies or lightly edited versions of each other
+var Dupes = cli.Command{
+	Name:  "dupes",
+	Usage: "qdox dupes [command options] [folder]",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:        "pattern, P",
+			Usage:       "only parse files matching regular expression",
+			Destination: &pattern,
+			Value:       "\\.txt$",
+		},
+		cli.Float64Flag{
+			Name:        "similarity, s",
+			Usage:       "required minimum resemblance of near-duplicates, 1 lists exact copies only",
+			Destination: &similarity,
+			Value:       0.9,
+		},
+	}, loadingFlags...),
+	Action: func(c *cli.Context) {
+		if len(c.Args()) < 1 {
+			fatal(fmt.Errorf("please provide source folder"))
+		}
+
+		patternr = regexp.MustCompile(pattern)
+		fatal(setFilter(c))
+		folder := path.Clean(c.Args().Get(0))
+
+		fatal(loadCorpus(&corpus, folder, filter))
+
+		for _, group := range corpus.Duplicates(similarity) {
+			fmt.Fprintf(c.App.Writer, "%q\n", corpus.GetPath(group.Original))
+			for i, v := range group.Copies {
+				if corpus.GetHash(v) == corpus.GetHash(group.Original) {
+					fmt.Fprintf(c.App.Writer, "  exact %q\n", corpus.GetPath(v))
+				} else {
+					fmt.Fprintf(c.App.Writer, "  %.0f%% %q\n", group.Similarities[i]*100.0, corpus.GetPath(v))
+				}
+			}
+		}
+	},
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/stormcrows/qdox/pkg/eval"
+	"github.com/stormcrows/qdox/pkg/nlp"
+	"github.com/urfave/cli"
+)
+
+// QueryMetrics holds retrieval quality measures of a single judged query
+type QueryMetrics struct {
+	ID    string
+	Query string
+	eval.Metrics
+}
+
+// EvalResponse lists measures of every judged query along with their means,
+// and ids of queries skipped for having no relevant documents
+type EvalResponse struct {
+	Queries []QueryMetrics
+	Overall eval.Metrics
+	Skipped []string
+}
+
+// Eval command trains the model and measures how well search results match relevance judgements
+var Eval = cli.Command{
+	Name:  "eval",
+	Usage: "qdox eval [command options] [folder] [judgements]",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:        "pattern, P",
+			Usage:       "only parse files matching regular expression",
+			Destination: &pattern,
+			Value:       "\\.txt$",
+		},
+		cli.IntFlag{
+			Name:        "k",
+			Usage:       "number of results considered per query",
+			Destination: &cutoff,
+			Value:       10,
+		},
+		cli.Float64Flag{
+			Name:        "threshold, t",
+			Usage:       "required minimum similarity per document",
+			Destination: &threshold,
+			Value:       0.3,
+		},
+		cli.StringFlag{
+			Name:        "queries, q",
+			Usage:       "file of \"qid query text\" lines, required by TREC qrels judgements",
+			Destination: &queries,
+		},
+		cli.StringFlag{
+			Name:        "format, f",
+			Usage:       "output format: table or json",
+			Destination: &format,
+			Value:       "table",
+		},
+	}, loadingFlags...),
+	Action: func(c *cli.Context) {
+		if len(c.Args()) < 2 {
+			fatal(fmt.Errorf("please provide source folder and judgements file"))
+		}
+
+		patternr = regexp.MustCompile(pattern)
+		fatal(setFilter(c))
+		folder := path.Clean(c.Args().Get(0))
+
+		judgements, err := loadJudgements(c.Args().Get(1), queries)
+		fatal(err)
+		judgements, skipped := measurable(judgements)
+
+		fatal(loadCorpus(&corpus, folder, filter))
+		fatal(model.Train(&corpus))
+
+		metrics := evaluate(model, folder, judgements, cutoff, threshold)
+		resp := EvalResponse{make([]QueryMetrics, len(judgements)), eval.Mean(metrics), skipped}
+		for i, j := range judgements {
+			resp.Queries[i] = QueryMetrics{j.ID, j.Query, metrics[i]}
+		}
+
+		switch format {
+		case "json":
+			body, err := json.Marshal(resp)
+			fatal(err)
+			fmt.Fprintln(c.App.Writer, string(body))
+		case "table":
+			w := tabwriter.NewWriter(c.App.Writer, 0, 8, 2, ' ', 0)
+			fmt.Fprintf(w, "query\tP@%d\tR@%d\tAP\tRR\tnDCG\n", cutoff, cutoff)
+			for _, q := range resp.Queries {
+				fmt.Fprintf(w, "%s\t%s\n", q.ID, formatMetrics(q.Metrics))
+			}
+			fmt.Fprintf(w, "all\t%s\n", formatMetrics(resp.Overall))
+			w.Flush()
+		default:
+			fatal(fmt.Errorf("unknown format %q", format))
+		}
+	},
+}
+
+// loadJudgements reads JSONL judgements, or TREC qrels along with their queries file
+func loadJudgements(file string, queriesFile string) ([]eval.Judgement, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if ext := strings.ToLower(filepath.Ext(file)); ext == ".jsonl" || ext == ".json" {
+		return eval.LoadJSONL(f)
+	}
+
+	if queriesFile == "" {
+		return nil, fmt.Errorf("please provide queries file for TREC qrels judgements")
+	}
+	q, err := os.Open(queriesFile)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+
+	return eval.LoadQrels(f, q)
+}
+
+// measurable drops judgements without relevant documents, noting their ids on stderr
+func measurable(judgements []eval.Judgement) ([]eval.Judgement, []string) {
+	judged, skipped := eval.Judged(judgements)
+	if len(skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "skipping %d queries without relevant documents: %s\n", len(skipped), strings.Join(skipped, ", "))
+	}
+	return judged, skipped
+}
+
+// evaluate runs judged queries through the model and measures their top k results
+func evaluate(m *nlp.Model, folder string, judgements []eval.Judgement, k int, threshold float64) []eval.Metrics {
+	metrics := make([]eval.Metrics, len(judgements))
+	for i, j := range judgements {
+		result := m.Query(j.Query, k, threshold)
+		ranked := make([]string, 0, len(result.Matched))
+		for _, v := range result.Matched {
+			ranked = append(ranked, judgedID(folder, m.Corpus.GetPath(v)))
+		}
+		metrics[i] = eval.Evaluate(ranked, j.Relevance, k)
+	}
+	return metrics
+}
+
+// judgedID identifies document the way judgements do, by its path relative to the folder,
+// so that documents sharing a name in different subfolders are never counted as one another
+func judgedID(folder string, p string) string {
+	if rel, err := filepath.Rel(folder, p); err == nil {
+		p = rel
+	}
+	return eval.DocumentID(p)
+}
+
+func formatMetrics(m eval.Metrics) string {
+	return fmt.Sprintf("%.3f\t%.3f\t%.3f\t%.3f\t%.3f", m.Precision, m.Recall, m.AP, m.RR, m.NDCG)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvalSkipsUnjudged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eval")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	judgements := filepath.Join(dir, "judgements.jsonl")
+	err = ioutil.WriteFile(judgements, []byte(`{"id": "park", "query": "national park", "relevant": ["Grand Teton National Park.txt"]}
+{"id": "none", "query": "sausages", "relevant": {"Grand Teton National Park.txt": 0}}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp()
+	buf := new(bytes.Buffer)
+	app.Writer = buf
+	app.Run([]string{"qdox", "eval", "-f", "json", "../books/", judgements})
+
+	resp := EvalResponse{}
+	if err := json.Unmarshal(buf.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"none"}, resp.Skipped, "query without relevant documents should be skipped")
+	if assert.Len(t, resp.Queries, 1, "only judged queries should be measured") {
+		assert.Equal(t, resp.Queries[0].Metrics, resp.Overall, "means should be of judged queries only")
+	}
+}
+
+func TestJudgedID(t *testing.T) {
+	assert.Equal(t, "a/park.txt", judgedID("docs", "docs/a/park.txt"), "documents should be identified by relative path")
+	assert.Equal(t, "park.txt", judgedID("docs", "docs/park.txt"), "documents should be identified by relative path")
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/stormcrows/qdox/pkg/nlp"
+	"github.com/urfave/cli"
+)
+
+// Explain command trains the model and breaks down the similarity of a query and a document
+var Explain = cli.Command{
+	Name:  "explain",
+	Usage: "qdox explain [command options] [folder] [query] [document]",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:        "pattern, P",
+			Usage:       "only parse files matching regular expression",
+			Destination: &pattern,
+			Value:       "\\.txt$",
+		},
+		cli.StringFlag{
+			Name:        "synonyms",
+			Usage:       "expands queries with Solr-style synonyms from given file",
+			Destination: &synonymsFile,
+		},
+		cli.IntFlag{
+			Name:        "expand, e",
+			Usage:       "expands queries with given number of closest terms per query term",
+			Destination: &expansion,
+		},
+		cli.StringFlag{
+			Name:        "format, f",
+			Usage:       "output format: table or json",
+			Destination: &format,
+			Value:       "table",
+		},
+	}, loadingFlags...),
+	Action: func(c *cli.Context) {
+		if len(c.Args()) < 3 {
+			fatal(fmt.Errorf("please provide source folder, query and document"))
+		}
+
+		patternr = regexp.MustCompile(pattern)
+		fatal(setFilter(c))
+		folder := path.Clean(c.Args().Get(0))
+
+		fatal(loadCorpus(&corpus, folder, filter))
+		model.Expansion = expansion
+		fatal(model.Train(&corpus))
+		fatal(loadSynonyms(synonymsFile))
+
+		doc, err := findDocument(c.Args().Get(2))
+		fatal(err)
+		e, err := model.Explain(c.Args().Get(1), doc)
+		fatal(err)
+
+		switch format {
+		case "json":
+			body, err := json.Marshal(e)
+			fatal(err)
+			fmt.Fprintln(c.App.Writer, string(body))
+		case "table":
+			writeExplanation(c.App.Writer, e)
+		default:
+			fatal(fmt.Errorf("unknown format %q", format))
+		}
+	},
+}
+
+// findDocument returns index of the trained document with given path, file name or index
+func findDocument(document string) (int, error) {
+	for i := 0; i < corpus.Len(); i++ {
+		if p := corpus.GetPath(i); p == path.Clean(document) || path.Base(p) == document {
+			return i, nil
+		}
+	}
+	if doc, err := strconv.Atoi(document); err == nil && doc >= 0 && doc < corpus.Len() {
+		return doc, nil
+	}
+	return 0, fmt.Errorf("document %q not found", document)
+}
+
+// scoredQuery returns the query text the results were scored for, which is the suggestion of corrected queries
+func scoredQuery(result nlp.QueryResult) string {
+	if result.Corrected {
+		return result.Suggestion
+	}
+	return result.Query
+}
+
+func writeExplanation(w io.Writer, e nlp.Explanation) {
+	fmt.Fprintf(w, "%.0f%% %q %q\n\n", e.Similarity*100.0, e.Query, corpus.GetPath(e.Document))
+
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "TERM\tVOCABULARY\tTF-IDF\tPROJECTIONS")
+	for _, t := range e.Terms {
+		vocabulary := "no"
+		if t.StopWord {
+			vocabulary = "stop word"
+		} else if t.InVocabulary {
+			vocabulary = "yes"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%.4f\t%s\n", t.Term, vocabulary, t.Weight, formatFloats(t.Projections))
+	}
+	fmt.Fprintln(table)
+
+	fmt.Fprintln(table, "COMPONENT\tQUERY\tDOCUMENT\tCONTRIBUTION")
+	for i, component := range e.Components {
+		fmt.Fprintf(table, "%d\t%.4f\t%.4f\t%+.4f\n", i+1, component.Query, component.Document, component.Contribution)
+	}
+	table.Flush()
+}
+
+func formatFloats(values []float64) string {
+	formatted := make([]string, len(values))
+	for i, v := range values {
+		formatted[i] = fmt.Sprintf("%+.4f", v)
+	}
+	return strings.Join(formatted, " ")
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/stormcrows/qdox/pkg/nlp"
+	"github.com/urfave/cli"
+)
+
+// Keywords command trains the model and lists the most characteristic terms of every document
+var Keywords = cli.Command{
+	Name:  "keywords",
+	Usage: "qdox keywords [command options] [folder]",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:        "pattern, P",
+			Usage:       "only parse files matching regular expression",
+			Destination: &pattern,
+			Value:       "\\.txt$",
+		},
+		cli.IntFlag{
+			Name:        "k",
+			Usage:       "number of keywords per document",
+			Destination: &keywords,
+			Value:       10,
+		},
+		cli.BoolFlag{
+			Name:        "keyphrases, b",
+			Usage:       "also lists bigram keyphrases",
+			Destination: &keyphrases,
+		},
+	}, loadingFlags...),
+	Action: func(c *cli.Context) {
+		if len(c.Args()) < 1 {
+			fatal(fmt.Errorf("please provide source folder"))
+		}
+
+		patternr = regexp.MustCompile(pattern)
+		fatal(setFilter(c))
+		folder := path.Clean(c.Args().Get(0))
+
+		fatal(loadCorpus(&corpus, folder, filter))
+		model.MaxKeywords = keywords
+		model.ExtractKeyphrases = keyphrases
+		fatal(model.Train(&corpus))
+
+		for i := 0; i < corpus.Len(); i++ {
+			fmt.Fprintf(c.App.Writer, "%q %s\n", corpus.GetPath(i), strings.Join(keywordTerms(model.Keywords(i, keywords)), ", "))
+			if keyphrases {
+				fmt.Fprintf(c.App.Writer, "  %s\n", strings.Join(keywordTerms(model.Keyphrases(i, keywords)), ", "))
+			}
+		}
+	},
+}
+
+func keywordTerms(keywords []nlp.Keyword) []string {
+	terms := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		terms[i] = keyword.Term
+	}
+	return terms
+}
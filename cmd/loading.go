@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/stormcrows/qdox/pkg/nlp"
+	"github.com/stormcrows/qdox/pkg/store"
+	"github.com/urfave/cli"
+)
+
+// loadingFlags filter files loaded into the corpus, shared by all commands loading it
+var loadingFlags = []cli.Flag{
+	cli.StringSliceFlag{
+		Name:  "exclude, x",
+		Usage: "skips paths matching gitignore-style pattern relative to the folder, can be repeated",
+	},
+	cli.StringFlag{
+		Name:        "max-size",
+		Usage:       "skips files larger than given size, e.g. 512KB or 10MB",
+		Destination: &maxSize,
+	},
+	cli.BoolFlag{
+		Name:        "hidden",
+		Usage:       "loads hidden directories, skipped by default",
+		Destination: &loadHidden,
+	},
+	cli.BoolFlag{
+		Name:        "archives",
+		Usage:       "loads members of zip and tar archives matching the pattern, by paths like bundle.zip!/dir/file.txt",
+		Destination: &loadArchives,
+	},
+	cli.BoolFlag{
+		Name:        "git",
+		Usage:       "loads files of the git repository in the folder as of --ref, identified by path@commit",
+		Destination: &gitRepository,
+	},
+	cli.StringFlag{
+		Name:        "ref",
+		Usage:       "branch, tag or commit of the git repository to load",
+		Destination: &gitRef,
+		Value:       "HEAD",
+	},
+	cli.BoolFlag{
+		Name:        "history",
+		Usage:       "loads past revisions of files of the git repository too",
+		Destination: &gitHistory,
+	},
+	cli.StringFlag{
+		Name:        "encoding",
+		Usage:       "legacy encoding of files neither valid UTF-8 nor starting with a byte order mark, e.g. iso-8859-2 or shift_jis",
+		Destination: &fallbackEncoding,
+		Value:       nlp.DefaultEncoding,
+	},
+	cli.StringFlag{
+		Name:        "normalisation",
+		Usage:       "Unicode normalisation form of documents: NFC, NFKC folding ligatures and full width letters, NFD, NFKD or none",
+		Destination: &normalisation,
+		Value:       "NFC",
+	},
+	cli.StringFlag{
+		Name:        "store",
+		Usage:       "keeps documents with their metadata and vectors in given file, reloading only changed ones on later runs",
+		Destination: &storeFile,
+	},
+}
+
+// setFilter sets filter of loaded files from the pattern and loading flags
+func setFilter(c *cli.Context) error {
+	size, err := parseSize(maxSize)
+	if err != nil {
+		return err
+	}
+	filter = nlp.Filter{
+		Pattern:       patternr,
+		Exclude:       c.StringSlice("exclude"),
+		MaxSize:       size,
+		Hidden:        loadHidden,
+		Archives:      loadArchives,
+		Encoding:      fallbackEncoding,
+		Normalisation: normalisation,
+	}
+	return filter.Validate()
+}
+
+// loadCorpus loads files of the folder passing the filter into the corpus, summing up skipped ones
+// and those reused from the store on stderr
+func loadCorpus(c *nlp.Corpus, folder string, f nlp.Filter) error {
+	if err := useStore(c); err != nil {
+		return err
+	}
+	if err := loadFiles(c, folder, f); err != nil {
+		return err
+	}
+	reportSkipped(c)
+	if documentStore != nil {
+		fmt.Fprintf(os.Stderr, "reused %d of %d documents from %s\n", c.Reused(), c.Len(), storeFile)
+	}
+	return nil
+}
+
+// reportSkipped sums up files and directories skipped by the last load of the corpus on stderr
+func reportSkipped(c *nlp.Corpus) {
+	if summary := skippedSummary(c.Skipped()); summary != "" {
+		fmt.Fprintln(os.Stderr, summary)
+	}
+}
+
+// useStore opens the store file given by the flag, once, and keeps documents of the corpus in it
+func useStore(c *nlp.Corpus) error {
+	if storeFile == "" {
+		return nil
+	}
+	if documentStore == nil {
+		s, err := store.Open(storeFile)
+		if err != nil {
+			return fmt.Errorf("failed to open store %s: %s", storeFile, err)
+		}
+		documentStore = s
+	}
+	c.UseStore(documentStore)
+	return nil
+}
+
+// loadFiles loads files of the folder, or of the git repository in it, passing the filter into the corpus
+func loadFiles(c *nlp.Corpus, folder string, f nlp.Filter) error {
+	if gitRepository {
+		return c.LoadRepository(folder, gitRef, gitHistory, f)
+	}
+	return c.LoadWith(folder, f)
+}
+
+// skippedSummary counts skipped files and directories by reason, e.g. "skipped 3: 2 binary, 1 hidden"
+func skippedSummary(skipped []nlp.Skipped) string {
+	if len(skipped) == 0 {
+		return ""
+	}
+	counts := make(map[string]int)
+	for _, s := range skipped {
+		counts[s.Reason]++
+	}
+	reasons := make([]string, 0, len(counts))
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if counts[reasons[i]] == counts[reasons[j]] {
+			return reasons[i] < reasons[j]
+		}
+		return counts[reasons[i]] > counts[reasons[j]]
+	})
+	for i, reason := range reasons {
+		reasons[i] = fmt.Sprintf("%d %s", counts[reason], reason)
+	}
+	return fmt.Sprintf("skipped %d: %s", len(skipped), strings.Join(reasons, ", "))
+}
+
+// parseSize parses number of bytes with an optional B, KB, MB or GB unit of powers of 1024, empty size is 0
+func parseSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	if s == "" {
+		return 0, nil
+	}
+	multiplier := int64(1)
+	for i, unit := range []string{"KB", "MB", "GB"} {
+		if strings.HasSuffix(s, unit) {
+			multiplier, s = int64(1)<<(10*uint(i+1)), strings.TrimSuffix(s, unit)
+			break
+		}
+	}
+	s = strings.TrimSpace(strings.TrimSuffix(s, "B"))
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/stormcrows/qdox/pkg/nlp"
+)
+
+// SearchResult is a single ranked search result written by machine-readable formats
+type SearchResult struct {
+	Query      string
+	Rank       int
+	Similarity float64
+	Semantic   *float64 `json:",omitempty"`
+	Lexical    *float64 `json:",omitempty"`
+	Path       string
+	Metadata   map[string]string `json:",omitempty"`
+	Snippet    string            `json:",omitempty"`
+	Versions   []SearchVersion   `json:",omitempty"`
+}
+
+// SearchVersion is a duplicate folded into the search result
+type SearchVersion struct {
+	Path        string
+	Resemblance float64
+}
+
+// SearchGroup holds results of one of the batch queries along with its spelling suggestion and expansions
+type SearchGroup struct {
+	Query      string
+	Expansions []string `json:",omitempty"`
+	Suggestion string   `json:",omitempty"`
+	Corrected  bool     `json:",omitempty"`
+	Results    []SearchResult
+}
+
+var searchColumns = []string{"query", "rank", "similarity", "semantic", "lexical", "path", "metadata", "snippet"}
+
+// newSearchResults describes query's results, with snippets of given number of words when positive
+func newSearchResults(result nlp.QueryResult, words int) []SearchResult {
+	results := make([]SearchResult, len(result.Matched))
+	for i, v := range result.Matched {
+		results[i] = SearchResult{
+			Query:      result.Query,
+			Rank:       i + 1,
+			Similarity: result.Similarities[i],
+			Path:       corpus.GetPath(v),
+			Metadata:   corpus.GetMetadata(v),
+		}
+		if result.Lexical != nil {
+			results[i].Semantic, results[i].Lexical = &result.Semantic[i], &result.Lexical[i]
+		}
+		if words > 0 {
+			results[i].Snippet, _ = currentSearcher().Snippet(v, result.Query, words)
+		}
+		if result.Versions != nil {
+			for _, version := range result.Versions[i] {
+				results[i].Versions = append(results[i].Versions, SearchVersion{corpus.GetPath(version), corpus.Resemblance(v, version)})
+			}
+		}
+	}
+	return results
+}
+
+func newSearchGroup(result nlp.QueryResult, words int) SearchGroup {
+	return SearchGroup{result.Query, result.Expansions, result.Suggestion, result.Corrected, newSearchResults(result, words)}
+}
+
+// resultsWriter writes search results in one of the output formats: text, json, jsonl, csv, tsv or table
+type resultsWriter struct {
+	w       io.Writer
+	format  string
+	started bool
+	all     []SearchResult
+	groups  []SearchGroup
+	csv     *csv.Writer
+	table   *tabwriter.Writer
+}
+
+func newResultsWriter(w io.Writer, format string) (*resultsWriter, error) {
+	rw := &resultsWriter{w: w, format: format, all: make([]SearchResult, 0)}
+	switch format {
+	case "text", "json", "jsonl":
+	case "csv":
+		rw.csv = csv.NewWriter(w)
+	case "tsv":
+		rw.csv = csv.NewWriter(w)
+		rw.csv.Comma = '\t'
+	case "table":
+		rw.table = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	return rw, nil
+}
+
+// Write outputs results right away, except for json which is written as a single array on Close
+func (rw *resultsWriter) Write(results []SearchResult) error {
+	defer func() { rw.started = true }()
+
+	switch rw.format {
+	case "text":
+		for _, r := range results {
+			if r.Lexical != nil {
+				fmt.Fprintf(rw.w, "%.0f%% %q semantic=%.0f%% lexical=%.2f\n", r.Similarity*100.0, r.Path, *r.Semantic*100.0, *r.Lexical)
+			} else {
+				fmt.Fprintf(rw.w, "%.0f%% %q\n", r.Similarity*100.0, r.Path)
+			}
+			for _, v := range r.Versions {
+				fmt.Fprintf(rw.w, "  = %.0f%% %q\n", v.Resemblance*100.0, v.Path)
+			}
+		}
+	case "json":
+		rw.all = append(rw.all, results...)
+	case "jsonl":
+		encoder := json.NewEncoder(rw.w)
+		for _, r := range results {
+			if err := encoder.Encode(r); err != nil {
+				return err
+			}
+		}
+	case "csv", "tsv":
+		if !rw.started {
+			rw.csv.Write(searchColumns)
+		}
+		for _, r := range results {
+			rw.csv.Write([]string{
+				r.Query,
+				strconv.Itoa(r.Rank),
+				strconv.FormatFloat(r.Similarity, 'f', -1, 64),
+				formatScore(r.Semantic, 'f', -1),
+				formatScore(r.Lexical, 'f', -1),
+				r.Path,
+				formatMetadata(r.Metadata),
+				r.Snippet,
+			})
+		}
+		rw.csv.Flush()
+		return rw.csv.Error()
+	case "table":
+		if !rw.started {
+			fmt.Fprintln(rw.table, strings.ToUpper(strings.Join(searchColumns, "\t")))
+		}
+		for _, r := range results {
+			fmt.Fprintf(rw.table, "%s\t%d\t%.0f%%\t%s\t%s\t%s\t%s\t%s\n", r.Query, r.Rank, r.Similarity*100.0, formatScore(r.Semantic, 'f', 2), formatScore(r.Lexical, 'f', 2), r.Path, formatMetadata(r.Metadata), r.Snippet)
+		}
+	}
+
+	return nil
+}
+
+// WriteGroup outputs results of one of the batch queries, json and jsonl nest them under their query
+func (rw *resultsWriter) WriteGroup(group SearchGroup) error {
+	switch rw.format {
+	case "text":
+		fmt.Fprintf(rw.w, "%q\n", group.Query)
+	case "json":
+		rw.groups = append(rw.groups, group)
+		return nil
+	case "jsonl":
+		return json.NewEncoder(rw.w).Encode(group)
+	}
+	return rw.Write(group.Results)
+}
+
+// Close flushes results buffered by json and table formats
+func (rw *resultsWriter) Close() error {
+	switch rw.format {
+	case "json":
+		var v interface{} = rw.all
+		if rw.groups != nil {
+			v = rw.groups
+		}
+		body, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(rw.w, string(body))
+		return err
+	case "table":
+		return rw.table.Flush()
+	}
+	return nil
+}
+
+// formatMetadata flattens metadata into sorted "key=value" pairs separated by semicolons
+func formatMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for k, v := range metadata {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ";")
+}
+
+// formatScore formats component score of hybrid results, empty for other ones
+func formatScore(score *float64, verb byte, prec int) string {
+	if score == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*score, verb, prec, 64)
+}
@@ -1,18 +1,24 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"os"
 	"path"
 	"regexp"
+	"runtime"
+	"strings"
+	"sync"
 
 	"github.com/urfave/cli"
 )
 
 // Search command loads the corpus, trains the model and returns with the results on the terminal
 var Search = cli.Command{
-	Name:  "search",
-	Usage: "qdox search [command options] [folder] [query]",
-	Flags: []cli.Flag{
+	Name:        "search",
+	Usage:       "qdox search [command options] [folder] [query]",
+	Description: "Without a query, searches every line of the --queries file or of stdin, training the model only once",
+	Flags: append([]cli.Flag{
 		cli.StringFlag{
 			Name:        "pattern, P",
 			Usage:       "only parse files matching regular expression",
@@ -31,28 +37,238 @@ var Search = cli.Command{
 			Destination: &threshold,
 			Value:       0.3,
 		},
-	},
+		cli.Float64Flag{
+			Name:        "collapse, c",
+			Usage:       "fold duplicates resembling at least given similarity into a single result, 0 disables",
+			Destination: &collapse,
+		},
+		cli.StringFlag{
+			Name:        "synonyms",
+			Usage:       "expands queries with Solr-style synonyms from given file",
+			Destination: &synonymsFile,
+		},
+		cli.IntFlag{
+			Name:        "expand, e",
+			Usage:       "expands queries with given number of closest terms per query term",
+			Destination: &expansion,
+		},
+		cli.BoolFlag{
+			Name:        "autocorrect, a",
+			Usage:       "reruns queries matching nothing with their suggested spelling",
+			Destination: &autoCorrect,
+		},
+		cli.StringFlag{
+			Name:        "fusion",
+			Usage:       "fuses LSI similarity with BM25 lexical score: sum for weighted sum, rrf for reciprocal rank fusion",
+			Destination: &fusion,
+		},
+		cli.Float64Flag{
+			Name:        "semantic-weight",
+			Usage:       "weight of LSI similarity in fusion",
+			Destination: &semanticWeight,
+			Value:       1.0,
+		},
+		cli.Float64Flag{
+			Name:        "lexical-weight",
+			Usage:       "weight of BM25 score in fusion",
+			Destination: &lexicalWeight,
+			Value:       1.0,
+		},
+		cli.StringFlag{
+			Name:        "shard-by",
+			Usage:       "splits corpus into shards trained in parallel, by top level subfolder or hash of path",
+			Destination: &shardBy,
+		},
+		cli.IntFlag{
+			Name:        "shards",
+			Usage:       "number of shards when sharding by hash",
+			Destination: &shardCount,
+			Value:       4,
+		},
+		cli.BoolFlag{
+			Name:        "randomised",
+			Usage:       "approximates SVD from random projection of documents, much faster and leaner on large corpora",
+			Destination: &randomised,
+		},
+		cli.IntFlag{
+			Name:        "oversampling",
+			Usage:       "random directions beyond the number of dimensions, more improve accuracy of randomised SVD",
+			Destination: &oversampling,
+			Value:       10,
+		},
+		cli.IntFlag{
+			Name:        "power-iterations",
+			Usage:       "passes refining randomised SVD, more improve accuracy but slow down training",
+			Destination: &powerIterations,
+			Value:       2,
+		},
+		cli.IntFlag{
+			Name:        "sample",
+			Usage:       "fits SVD to given number of randomly chosen documents and folds the rest in, 0 fits all of them",
+			Destination: &trainingSample,
+		},
+		cli.BoolFlag{
+			Name:        "training-stats",
+			Usage:       "prints time and memory taken by training to stderr",
+			Destination: &showTrainingStats,
+		},
+		cli.IntFlag{
+			Name:        "ann-m",
+			Usage:       "links per node of approximate nearest neighbour index of documents, 0 disables it",
+			Destination: &annM,
+		},
+		cli.IntFlag{
+			Name:        "ann-ef-construction",
+			Usage:       "candidates considered while building the index, more improve recall",
+			Destination: &annEfConstruction,
+			Value:       200,
+		},
+		cli.IntFlag{
+			Name:        "ann-ef-search",
+			Usage:       "candidates considered by indexed queries, more improve recall but increase latency",
+			Destination: &annEfSearch,
+			Value:       64,
+		},
+		cli.IntFlag{
+			Name:        "ann-min-documents",
+			Usage:       "number of documents below which all of them are scanned instead of building the index",
+			Destination: &annMinDocuments,
+			Value:       10000,
+		},
+		cli.StringFlag{
+			Name:        "queries, q",
+			Usage:       "file of queries to search, one per line, \"-\" reads them from stdin",
+			Destination: &queries,
+		},
+		cli.StringFlag{
+			Name:        "format, f",
+			Usage:       "output format: text, json, jsonl, csv, tsv or table",
+			Destination: &format,
+			Value:       "text",
+		},
+		cli.IntFlag{
+			Name:        "snippet-words, w",
+			Usage:       "number of words in snippets of formats other than text, 0 disables them",
+			Destination: &snippetWords,
+			Value:       30,
+		},
+	}, loadingFlags...),
 	Action: func(c *cli.Context) {
-		if len(c.Args()) < 2 {
+		if len(c.Args()) < 1 {
 			fatal(fmt.Errorf("please provide source folder and query"))
 		}
+		if len(c.Args()) > 1 && queries != "" {
+			fatal(fmt.Errorf("please provide either a query or a file of queries, not both"))
+		}
 
 		patternr = regexp.MustCompile(pattern)
+		fatal(setFilter(c))
 		folder := path.Clean(c.Args().Get(0))
 		query := c.Args().Get(1)
 
-		fatal(corpus.Load(folder, patternr))
-		fatal(model.Train(&corpus))
+		w, err := newResultsWriter(c.App.Writer, format)
+		fatal(err)
+
+		var batch []string
+		if len(c.Args()) < 2 {
+			batch, err = readQueries(queries)
+			fatal(err)
+		}
+
+		fatal(loadCorpus(&corpus, folder, filter))
+		fatal(train(folder))
+		if showTrainingStats {
+			for _, line := range trainingStats() {
+				fmt.Fprintln(os.Stderr, line)
+			}
+		}
+		fatal(loadSynonyms(synonymsFile))
+
+		words := snippetWords
+		if format == "text" {
+			words = 0
+		}
+
+		if batch != nil {
+			groups, err := searchAll(batch, words)
+			fatal(err)
+			for _, group := range groups {
+				fatal(w.WriteGroup(group))
+			}
+			fatal(w.Close())
+			return
+		}
 
-		result := model.Query(query, n, threshold)
+		result := currentSearcher().Query(query, n, threshold)
 		fatal(result.Err)
 
-		for i, v := range result.Matched {
-			fmt.Fprintf(c.App.Writer, "%.0f%% %q\n", result.Similarities[i]*100.0, corpus.GetPath(v))
+		if len(result.Expansions) > 0 {
+			fmt.Fprintf(os.Stderr, "expanded with: %s\n", strings.Join(result.Expansions, ", "))
 		}
+		if result.Corrected {
+			fmt.Fprintf(os.Stderr, "showing results for: %q\n", result.Suggestion)
+		} else if result.Suggestion != "" {
+			fmt.Fprintf(os.Stderr, "did you mean: %q\n", result.Suggestion)
+		}
+
+		fatal(w.Write(newSearchResults(result, words)))
+		fatal(w.Close())
 	},
 }
 
+// readQueries reads non-blank lines of the queries file, or of stdin when the file is "-" or not given
+func readQueries(file string) ([]string, error) {
+	r := stdin
+	if file != "" && file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	batch := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if q := strings.TrimSpace(scanner.Text()); q != "" {
+			batch = append(batch, q)
+		}
+	}
+	return batch, scanner.Err()
+}
+
+// searchAll scores the queries in parallel on the trained model, returning their results in the order of queries
+func searchAll(batch []string, words int) ([]SearchGroup, error) {
+	groups := make([]SearchGroup, len(batch))
+	errs := make([]error, len(batch))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				result := currentSearcher().Query(batch[j], n, threshold)
+				groups[j], errs[j] = newSearchGroup(result, words), result.Err
+			}
+		}()
+	}
+	for j := range batch {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
 func fatal(err error) {
 	if err != nil {
 		panic(err)
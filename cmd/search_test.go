@@ -2,6 +2,10 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,3 +40,77 @@ func TestSearchWithDifferentThreshold(t *testing.T) {
 	expected := "92% \"../books/Grand Teton National Park.txt\"\n"
 	assert.Equal(t, expected, buf.String(), "different results")
 }
+
+func TestSearchWithJSONLFormat(t *testing.T) {
+	app := NewApp()
+	buf := new(bytes.Buffer)
+	app.Writer = buf
+	app.Run([]string{"qdox", "search", "../books/", "wild weekend", "-f", "jsonl", "-w", "5"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2, "expected a line per result")
+
+	result := SearchResult{}
+	if err := json.Unmarshal([]byte(lines[0]), &result); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "wild weekend", result.Query, "different query")
+	assert.Equal(t, 1, result.Rank, "different rank")
+	assert.Equal(t, "../books/Grand Teton National Park.txt", result.Path, "different path")
+	assert.InDelta(t, 0.92, result.Similarity, 0.005, "different similarity")
+	assert.Equal(t, "50314", result.Metadata["size"], "different size")
+	assert.Contains(t, strings.ToLower(result.Snippet), "wild", "snippet should contain query term")
+}
+
+func TestSearchWithCSVFormat(t *testing.T) {
+	app := NewApp()
+	buf := new(bytes.Buffer)
+	app.Writer = buf
+	app.Run([]string{"qdox", "search", "../books/", "wild weekend", "-n", "1", "-f", "csv", "-w", "0"})
+
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, records, 2, "expected header and a single result")
+	assert.Equal(t, searchColumns, records[0], "different header")
+	assert.Equal(t, []string{"wild weekend", "1"}, records[1][:2], "different result")
+	assert.Equal(t, "", records[1][7], "snippet should be disabled")
+	assert.Equal(t, "", records[1][4], "lexical score should be empty without fusion")
+}
+
+func TestSearchBatchFromStdin(t *testing.T) {
+	stdin = strings.NewReader("wild weekend\n\nknight of valour\n")
+	defer func() { stdin = os.Stdin }()
+
+	app := NewApp()
+	buf := new(bytes.Buffer)
+	app.Writer = buf
+	app.Run([]string{"qdox", "search", "../books/", "-f", "jsonl", "-w", "0"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2, "expected a line per query")
+
+	groups := make([]SearchGroup, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &groups[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, "wild weekend", groups[0].Query, "queries should keep their order")
+	assert.Empty(t, groups[0].Suggestion, "queries matching better than their suggestion should not have one")
+	assert.Len(t, groups[0].Results, 2, "different number of results")
+	assert.Equal(t, "../books/Grand Teton National Park.txt", groups[0].Results[0].Path, "different result")
+	assert.Equal(t, "knight of valour", groups[1].Query, "queries should keep their order")
+	assert.Equal(t, "knight of valour", groups[1].Results[0].Query, "results should belong to their query")
+}
+
+func TestSearchQueryWithQueriesFile(t *testing.T) {
+	defer func() { queries = "" }()
+
+	app := NewApp()
+	app.Writer = new(bytes.Buffer)
+	assert.PanicsWithError(t, "please provide either a query or a file of queries, not both", func() {
+		app.Run([]string{"qdox", "search", "../books/", "wild weekend", "-q", "-"})
+	}, "query along with queries file should be rejected")
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -8,12 +9,16 @@ import (
 	"os"
 	"os/signal"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"syscall"
 	"text/template"
 	"time"
 
+	"github.com/stormcrows/qdox/pkg/cache"
+	"github.com/stormcrows/qdox/pkg/nlp"
 	"github.com/stormcrows/qdox/pkg/watcher"
 
 	"github.com/urfave/cli"
@@ -21,15 +26,22 @@ import (
 
 // Result defines a single search result
 type Result struct {
-	Name       string
-	Path       string
-	Similarity string
+	Name        string
+	Path        string
+	Similarity  string
+	Semantic    string           `json:",omitempty"`
+	Lexical     string           `json:",omitempty"`
+	Versions    []Result         `json:",omitempty"`
+	Explanation *nlp.Explanation `json:",omitempty"`
 }
 
 // QueryResponse is JSON response to /query requests
 type QueryResponse struct {
-	Query   string
-	Results []Result
+	Query      string
+	Results    []Result
+	Expansions []string `json:",omitempty"`
+	Suggestion string   `json:",omitempty"`
+	Corrected  bool     `json:",omitempty"`
 }
 
 // Tpl holds compiled templates for execution
@@ -45,7 +57,7 @@ var (
 var Serve = cli.Command{
 	Name:  "serve",
 	Usage: "qdox serve [command options] [folder]",
-	Flags: []cli.Flag{
+	Flags: append([]cli.Flag{
 		cli.IntFlag{
 			Name:        "port, p",
 			Usage:       "starts serving at given port",
@@ -79,31 +91,162 @@ var Serve = cli.Command{
 			Usage:       "simple query ui served at /index level",
 			Destination: &interact,
 		},
-	},
+		cli.BoolFlag{
+			Name:        "keyphrases, k",
+			Usage:       "extracts bigram keyphrases of documents listed under /documents path",
+			Destination: &keyphrases,
+		},
+		cli.Float64Flag{
+			Name:        "collapse, c",
+			Usage:       "fold duplicates resembling at least given similarity into a single result, 0 disables",
+			Destination: &collapse,
+		},
+		cli.StringFlag{
+			Name:        "synonyms",
+			Usage:       "expands queries with Solr-style synonyms from given file",
+			Destination: &synonymsFile,
+		},
+		cli.IntFlag{
+			Name:        "expand, e",
+			Usage:       "expands queries with given number of closest terms per query term",
+			Destination: &expansion,
+		},
+		cli.BoolFlag{
+			Name:        "autocorrect, a",
+			Usage:       "reruns queries matching nothing with their suggested spelling",
+			Destination: &autoCorrect,
+		},
+		cli.StringFlag{
+			Name:        "fusion",
+			Usage:       "fuses LSI similarity with BM25 lexical score: sum for weighted sum, rrf for reciprocal rank fusion",
+			Destination: &fusion,
+		},
+		cli.Float64Flag{
+			Name:        "semantic-weight",
+			Usage:       "weight of LSI similarity in fusion",
+			Destination: &semanticWeight,
+			Value:       1.0,
+		},
+		cli.Float64Flag{
+			Name:        "lexical-weight",
+			Usage:       "weight of BM25 score in fusion",
+			Destination: &lexicalWeight,
+			Value:       1.0,
+		},
+		cli.IntFlag{
+			Name:        "cache-size",
+			Usage:       "number of query responses cached until the model changes, 0 disables the cache",
+			Destination: &cacheSize,
+			Value:       1000,
+		},
+		cli.DurationFlag{
+			Name:        "cache-ttl",
+			Usage:       "time query responses stay cached, 0 keeps them until evicted",
+			Destination: &cacheTTL,
+			Value:       5 * time.Minute,
+		},
+		cli.StringFlag{
+			Name:        "shard-by",
+			Usage:       "splits corpus into shards trained in parallel, by top level subfolder or hash of path",
+			Destination: &shardBy,
+		},
+		cli.IntFlag{
+			Name:        "shards",
+			Usage:       "number of shards when sharding by hash",
+			Destination: &shardCount,
+			Value:       4,
+		},
+		cli.BoolFlag{
+			Name:        "randomised",
+			Usage:       "approximates SVD from random projection of documents, much faster and leaner on large corpora",
+			Destination: &randomised,
+		},
+		cli.IntFlag{
+			Name:        "oversampling",
+			Usage:       "random directions beyond the number of dimensions, more improve accuracy of randomised SVD",
+			Destination: &oversampling,
+			Value:       10,
+		},
+		cli.IntFlag{
+			Name:        "power-iterations",
+			Usage:       "passes refining randomised SVD, more improve accuracy but slow down training",
+			Destination: &powerIterations,
+			Value:       2,
+		},
+		cli.IntFlag{
+			Name:        "sample",
+			Usage:       "fits SVD to given number of randomly chosen documents and folds the rest in, 0 fits all of them",
+			Destination: &trainingSample,
+		},
+		cli.IntFlag{
+			Name:        "ann-m",
+			Usage:       "links per node of approximate nearest neighbour index of documents, 0 disables it",
+			Destination: &annM,
+		},
+		cli.IntFlag{
+			Name:        "ann-ef-construction",
+			Usage:       "candidates considered while building the index, more improve recall",
+			Destination: &annEfConstruction,
+			Value:       200,
+		},
+		cli.IntFlag{
+			Name:        "ann-ef-search",
+			Usage:       "candidates considered by indexed queries, more improve recall but increase latency",
+			Destination: &annEfSearch,
+			Value:       64,
+		},
+		cli.IntFlag{
+			Name:        "ann-min-documents",
+			Usage:       "number of documents below which all of them are scanned instead of building the index",
+			Destination: &annMinDocuments,
+			Value:       10000,
+		},
+	}, loadingFlags...),
 	Action: func(c *cli.Context) (err error) {
 		// args
 		if len(c.Args()) < 1 {
 			return fmt.Errorf("please provide folder path")
 		}
+		if shardBy != "" && watcherEnabled {
+			return fmt.Errorf("watcher does not support shards")
+		}
+		if gitRepository && watcherEnabled {
+			return fmt.Errorf("watcher does not support git repositories")
+		}
 
 		if Tpl == nil {
 			Tpl = template.Must(template.ParseGlob("templates/*.gohtml"))
 		}
 		patternr = regexp.MustCompile(pattern)
+		if err = setFilter(c); err != nil {
+			return err
+		}
 		folder := c.Args().Get(0)
 
 		// nlp
-		corpus.Load(folder, patternr)
-		err = model.Train(&corpus)
-		if err != nil {
-			panic(err)
+		if err = loadCorpus(&corpus, folder, filter); err != nil {
+			return err
+		}
+		model.ExtractKeyphrases = keyphrases
+		if err = train(folder); err != nil {
+			return err
+		}
+		for _, line := range trainingStats() {
+			log.Println(line)
+		}
+		if err = loadSynonyms(synonymsFile); err != nil {
+			return err
+		}
+
+		if cacheSize > 0 {
+			queryCache = cache.NewLRU(cacheSize, cacheTTL)
 		}
 
 		// watcher
 		if watcherEnabled {
 			watcher := &watcher.Watcher{
 				MaxEvents: 10,
-				Handler:   watcher.FileHandler(folder, patternr, &corpus, model),
+				Handler:   watcher.FileHandler(folder, filter, &corpus, model),
 				Folder:    folder,
 				Interval:  time.Millisecond * time.Duration(interval),
 				Pattern:   patternr,
@@ -143,14 +286,29 @@ var Serve = cli.Command{
 
 		// routes
 		if serveFiles {
-			fs := http.StripPrefix("/static/", http.FileServer(http.Dir(folder)))
-			http.Handle("/static/", fs)
+			servedFolder = folder
+			http.Handle("/static/", StaticHandler(folder))
 		}
 		if interact {
 			http.HandleFunc("/", IndexHandler)
 		}
 		http.HandleFunc("/query", QueryHandler)
 		http.HandleFunc("/query/", QueryHandler)
+		if shards == nil {
+			http.HandleFunc("/clusters", ClustersHandler)
+			http.HandleFunc("/clusters/", ClustersHandler)
+			http.HandleFunc("/topics", TopicsHandler)
+			http.HandleFunc("/topics/", TopicsHandler)
+			http.HandleFunc("/documents", DocumentsHandler)
+			http.HandleFunc("/documents/", DocumentsHandler)
+		} else {
+			for _, route := range []string{"/clusters", "/clusters/", "/topics", "/topics/", "/documents", "/documents/"} {
+				http.HandleFunc(route, ShardedHandler)
+			}
+		}
+		http.HandleFunc("/suggest", SuggestHandler)
+		http.HandleFunc("/suggest/", SuggestHandler)
+		http.HandleFunc("/cache", CacheHandler)
 
 		// serve
 		fmt.Printf("qdox listening on port: %d\n", port)
@@ -165,6 +323,11 @@ func IndexHandler(w http.ResponseWriter, r *http.Request) {
 	Tpl.ExecuteTemplate(w, "interaction.gohtml", defaultResponse)
 }
 
+// ShardedHandler responds to routes needing the model of the whole corpus, which sharded corpora do not have
+func ShardedHandler(w http.ResponseWriter, r *http.Request) {
+	respond(http.StatusNotImplemented, fmt.Sprintf("%s is not supported with shards", r.URL.Path), w)
+}
+
 // QueryHandler handles search queries and responds with JSON
 func QueryHandler(w http.ResponseWriter, r *http.Request) {
 	// args
@@ -195,35 +358,105 @@ func QueryHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	log.Println(fmt.Sprintf("ip=%s, query=%q, n=%d, t=%.2f", r.RemoteAddr, q, n, threshold))
+	explain := false
+	if args.Get("explain") != "" {
+		explain, err = strconv.ParseBool(args.Get("explain"))
+		if err != nil {
+			respond(http.StatusBadRequest, "explain should be a boolean", w)
+			return
+		}
+	}
 
-	// nlp query
-	result := model.Query(q, n, threshold)
-	if result.Err != nil {
-		log.Println(fmt.Sprintf("query error: %s", result.Err))
-		respond(http.StatusInternalServerError, "", w)
+	var asOf time.Time
+	if args.Get("as_of") != "" {
+		asOf, err = parseAsOf(args.Get("as_of"))
+		if err != nil {
+			respond(http.StatusBadRequest, "as_of should be a RFC 3339 timestamp or a date", w)
+			return
+		}
+		if documentStore == nil || shards != nil {
+			respond(http.StatusBadRequest, "as_of needs a store and no shards", w)
+			return
+		}
+		if explain {
+			respond(http.StatusBadRequest, "as_of does not support explain", w)
+			return
+		}
+		if args.Get("fusion") != "" || args.Get("semantic") != "" || args.Get("lexical") != "" {
+			respond(http.StatusBadRequest, "as_of ranks by LSI similarity only and does not support fusion", w)
+			return
+		}
+	}
+
+	fusion := nlp.Fusion{Method: "", Semantic: semanticWeight, Lexical: lexicalWeight}
+	if shards == nil {
+		fusion = model.Fusion
+	} else if len(shards.Shards) > 0 {
+		fusion = shards.Shards[0].Model.Fusion
+	}
+	if args.Get("fusion") != "" {
+		fusion.Method = args.Get("fusion")
+	}
+	if args.Get("semantic") != "" {
+		fusion.Semantic, err = strconv.ParseFloat(args.Get("semantic"), 64)
+		if err != nil {
+			respond(http.StatusBadRequest, "semantic should be a non-negative float number", w)
+			return
+		}
+	}
+	if args.Get("lexical") != "" {
+		fusion.Lexical, err = strconv.ParseFloat(args.Get("lexical"), 64)
+		if err != nil {
+			respond(http.StatusBadRequest, "lexical should be a non-negative float number", w)
+			return
+		}
+	}
+	if err = fusion.Validate(); err != nil {
+		respond(http.StatusBadRequest, err.Error(), w)
 		return
 	}
+	if !asOf.IsZero() {
+		fusion = nlp.Fusion{}
+	}
 
-	// response
-	resp := QueryResponse{q, make([]Result, len(result.Matched))}
+	log.Println(fmt.Sprintf("ip=%s, query=%q, n=%d, t=%.2f, explain=%t, fusion=%v, as_of=%q", r.RemoteAddr, q, n, threshold, explain, fusion, args.Get("as_of")))
 
-	for i, v := range result.Matched {
-		name := path.Base(model.Corpus.GetPath(v))
-		path := ""
-		if serveFiles {
-			path = fmt.Sprintf("static/%s", name)
+	// nlp query, unless its response is cached
+	key := queryCacheKey(q, n, threshold, fusion, explain, asOf)
+	resp, cached := cachedQueryResponse(key)
+	if !cached {
+		generation := currentSearcher().Generation()
+		var result nlp.QueryResult
+		if asOf.IsZero() {
+			result = currentSearcher().QueryWith(q, n, threshold, fusion)
+		} else {
+			result = model.QueryAsOf(q, n, threshold, asOf)
+		}
+		if result.Err != nil {
+			log.Println(fmt.Sprintf("query error: %s", result.Err))
+			respond(http.StatusInternalServerError, "", w)
+			return
 		}
-		resp.Results[i] = Result{
-			name,
-			path,
-			fmt.Sprintf("%.0f", result.Similarities[i]*100.0),
+
+		resp, err = newQueryResponse(result, explain)
+		if err != nil {
+			log.Println(fmt.Sprintf("explanation error: %s", err))
+			respond(http.StatusInternalServerError, "", w)
+			return
 		}
+		cacheQueryResponse(key, generation, resp)
+	}
+	resp.Query = q
+
+	// only queries finding something are worth suggesting
+	if len(resp.Results) > 0 {
+		popularQueries.Add(normaliseQuery(q), 1)
 	}
 
+	// response
 	body, err := json.Marshal(resp)
 	if err != nil {
-		log.Println(fmt.Sprintf("json marshalling error: %s", result.Err))
+		log.Println(fmt.Sprintf("json marshalling error: %s", err))
 		respond(http.StatusInternalServerError, "", w)
 		return
 	}
@@ -234,6 +467,111 @@ func QueryHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println(fmt.Sprintf("response: %s", string(body)))
 }
 
+// parseAsOf parses time of point-in-time queries, either RFC 3339 timestamp or a date meaning its start in UTC
+func parseAsOf(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// newQueryResponse describes results of the query, explaining their similarities if asked to
+func newQueryResponse(result nlp.QueryResult, explain bool) (QueryResponse, error) {
+	resp := QueryResponse{result.Query, make([]Result, len(result.Matched)), result.Expansions, result.Suggestion, result.Corrected}
+
+	for i, v := range result.Matched {
+		if v < 0 {
+			// documents removed since point-in-time queries have no file to link to
+			resp.Results[i] = Result{Name: path.Base(result.Paths[i]), Similarity: fmt.Sprintf("%.0f", result.Similarities[i]*100.0)}
+			continue
+		}
+		resp.Results[i] = newResult(v, result.Similarities[i])
+		if result.Lexical != nil {
+			resp.Results[i].Semantic = fmt.Sprintf("%.0f", result.Semantic[i]*100.0)
+			resp.Results[i].Lexical = fmt.Sprintf("%.2f", result.Lexical[i])
+		}
+		if result.Versions != nil {
+			for _, version := range result.Versions[i] {
+				resp.Results[i].Versions = append(resp.Results[i].Versions, newResult(version, corpus.Resemblance(v, version)))
+			}
+		}
+		if explain {
+			explanation, err := currentSearcher().Explain(scoredQuery(result), v)
+			if err != nil {
+				return resp, err
+			}
+			resp.Results[i].Explanation = &explanation
+		}
+	}
+
+	return resp, nil
+}
+
+// serveRevision serves content of the document at the path if it is a revision of a file of the git repository,
+// read back from the repository as revisions have no files, telling whether it is one
+func serveRevision(w http.ResponseWriter, r *http.Request, p string) bool {
+	i := strings.LastIndex(p, nlp.RevisionSeparator)
+	if i < 0 {
+		return false
+	}
+	for doc, docPath := range corpus.Paths() {
+		if docPath != p {
+			continue
+		}
+		text, err := corpus.Read(doc)
+		if err != nil {
+			log.Println(fmt.Sprintf("revision %s error: %s", p, err))
+			respond(http.StatusInternalServerError, "", w)
+			return true
+		}
+		modified, _ := time.Parse(time.RFC3339, corpus.GetMetadata(doc)["modified"])
+		http.ServeContent(w, r, filepath.Base(p[:i]), modified, strings.NewReader(text))
+		return true
+	}
+	return false
+}
+
+// StaticHandler serves files of the folder under /static/ path, along with members of archives and revisions of files of the git repository
+// by their virtual paths
+func StaticHandler(folder string) http.Handler {
+	files := http.FileServer(http.Dir(folder))
+	return http.StripPrefix("/static/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gitRepository && serveRevision(w, r, filepath.Join(folder, filepath.FromSlash(path.Clean("/"+r.URL.Path)))) {
+			return
+		}
+		archive, member, ok := nlp.SplitArchivePath(r.URL.Path)
+		if !ok {
+			files.ServeHTTP(w, r)
+			return
+		}
+
+		archive = filepath.Join(folder, filepath.FromSlash(path.Clean("/"+archive)))
+		content, modified, err := nlp.ReadArchived(archive + nlp.ArchiveSeparator + member)
+		if err != nil {
+			respond(http.StatusNotFound, "", w)
+			return
+		}
+		http.ServeContent(w, r, path.Base(member), modified, bytes.NewReader(content))
+	}))
+}
+
+// newResult describes trained document with given index and its similarity
+func newResult(doc int, similarity float64) Result {
+	name := path.Base(corpus.GetPath(doc))
+	path := ""
+	if serveFiles {
+		path = "static/" + name
+		if rel, err := filepath.Rel(servedFolder, corpus.GetPath(doc)); servedFolder != "" && err == nil && !strings.HasPrefix(rel, "..") {
+			path = "static/" + filepath.ToSlash(rel)
+		}
+	}
+	return Result{
+		Name:       name,
+		Path:       path,
+		Similarity: fmt.Sprintf("%.0f", similarity*100.0),
+	}
+}
+
 func respond(code int, body string, w http.ResponseWriter) {
 	w.WriteHeader(code)
 	if body == "" {
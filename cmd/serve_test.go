@@ -1,14 +1,26 @@
 package cmd
 
 import (
+	"archive/zip"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stormcrows/qdox/pkg/cache"
+	"github.com/stormcrows/qdox/pkg/nlp"
+	"github.com/stormcrows/qdox/pkg/store"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -186,5 +198,555 @@ func testResponse(t *testing.T, q string, n string, threshold string, want *Quer
 		t.Fatal(err)
 	}
 
-	assert.Equal(t, *want, qresp, "query response different from expected")
+	if want != nil {
+		assert.Equal(t, *want, qresp, "query response different from expected")
+	}
+}
+
+func TestClusters(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	req, err := http.NewRequest("GET", "/clusters?k=2&terms=3", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ClustersHandler)
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+
+	resp := ClustersResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, resp.Clusters, 2, "incorrect number of clusters")
+	for _, cluster := range resp.Clusters {
+		assert.Len(t, cluster.Terms, 3, "incorrect number of terms")
+	}
+}
+
+func TestClustersParamsErrors(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	for _, query := range []string{"k=0", "k=x", "terms=-1"} {
+		req, err := http.NewRequest("GET", "/clusters?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(ClustersHandler)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "incorrect status code for %s", query)
+	}
+}
+
+func TestTopics(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	req, err := http.NewRequest("GET", "/topics?terms=3&documents=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(TopicsHandler)
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+
+	resp := TopicsResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, resp.Topics, 4, "incorrect number of topics")
+	for _, topic := range resp.Topics {
+		assert.Len(t, topic.Terms, 3, "incorrect number of terms")
+		assert.Len(t, topic.Documents, 1, "incorrect number of documents")
+	}
+}
+
+func TestDocuments(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(DocumentsHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/documents", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+
+	docs := make([]DocumentResponse, 0)
+	if err := json.Unmarshal(rr.Body.Bytes(), &docs); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, docs, 4, "incorrect number of documents")
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(DocumentsHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/documents/2?k=3", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+
+	doc := DocumentResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Grand Teton National Park.txt", doc.Name, "incorrect document")
+	assert.Equal(t, []string{"teton", "park", "jackson"}, doc.Keywords, "incorrect keywords")
+
+	for _, path := range []string{"/documents/4", "/documents/x"} {
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(DocumentsHandler).ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code, "incorrect status code for %s", path)
+	}
+
+	// no more keywords than retained at training can be asked for
+	for _, path := range []string{"/documents/2?k=-1", "/documents/2?k=20"} {
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(DocumentsHandler).ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "incorrect status code for %s", path)
+	}
+}
+
+func TestDocumentsStore(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := store.Open(filepath.Join(dir, "qdox.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	record := store.Record{Path: model.Corpus.GetPath(2), Content: "stored content", Hash: "abc", Metadata: map[string]string{"size": "14"}, Vector: []float64{0.5, 0.25}}
+	if err := s.Put(record); err != nil {
+		t.Fatal(err)
+	}
+	documentStore = s
+	defer func() { documentStore = nil }()
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(DocumentsHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/documents/2?content=true", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+
+	doc := DocumentResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "stored content", doc.Content, "content should be read from the store")
+	assert.Equal(t, record.Hash, doc.Hash, "incorrect hash")
+	assert.Equal(t, record.Metadata, doc.Metadata, "incorrect metadata")
+	assert.Equal(t, record.Vector, doc.Vector, "incorrect vector")
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(DocumentsHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/documents/2", nil))
+	doc = DocumentResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, doc.Content, "content should be left out unless asked for")
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(DocumentsHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/documents/1", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "documents missing from the store should not be found")
+
+	// the store lists documents
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(DocumentsHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/documents", nil))
+	docs := make([]DocumentResponse, 0)
+	if err := json.Unmarshal(rr.Body.Bytes(), &docs); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, docs, 1, "only stored documents should be listed") {
+		assert.Equal(t, 2, docs[0].ID, "incorrect id")
+		assert.Equal(t, record.Hash, docs[0].Hash, "incorrect hash")
+	}
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(DocumentsHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/documents/2?content=x", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "incorrect status code")
+}
+
+func TestDocumentsHistory(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := store.Open(filepath.Join(dir, "qdox.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for target, handler := range map[string]http.HandlerFunc{"/documents/2/history": DocumentsHandler, "/query?q=park&as_of=2020-01-01": QueryHandler} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "%s should need a store", target)
+	}
+
+	// books are written on the first day, except the park one which was about sausages until the second day
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	folder := filepath.Join(dir, "books")
+	if err := os.Mkdir(folder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name string, content []byte, modified time.Time) {
+		path := filepath.Join(folder, name)
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	books, err := ioutil.ReadDir("../books")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, book := range books {
+		content, err := ioutil.ReadFile(filepath.Join("../books", book.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		write(book.Name(), content, day(1))
+	}
+	park, err := ioutil.ReadFile("../books/Grand Teton National Park.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("Grand Teton National Park.txt", []byte("sausage makers"), day(1))
+
+	saved, savedModel := corpus, model
+	defer func() {
+		corpus, model, documentStore = saved, savedModel, nil
+	}()
+	corpus, model, documentStore = nlp.NewCorpus(), nlp.NewLSIModel(), s
+	corpus.UseStore(s)
+	filter := nlp.Filter{Pattern: regexp.MustCompile("\\.txt$")}
+	if err := corpus.LoadWith(folder, filter); err != nil {
+		t.Fatal(err)
+	}
+	write("Grand Teton National Park.txt", park, day(2))
+	if err := corpus.LoadWith(folder, filter); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.Train(&corpus); err != nil {
+		t.Fatal(err)
+	}
+	id := -1
+	for i, path := range corpus.Paths() {
+		if filepath.Base(path) == "Grand Teton National Park.txt" {
+			id = i
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(DocumentsHandler).ServeHTTP(rr, httptest.NewRequest("GET", fmt.Sprintf("/documents/%d/history?content=true", id), nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+
+	revisions := make([]RevisionResponse, 0)
+	if err := json.Unmarshal(rr.Body.Bytes(), &revisions); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, revisions, 2, "incorrect number of revisions") {
+		assert.Equal(t, "sausage makers", revisions[0].Content, "revisions should be listed oldest first")
+		if assert.NotNil(t, revisions[0].Replaced, "past revision should be replaced") {
+			assert.True(t, day(2).Equal(*revisions[0].Replaced), "incorrect replacement time")
+		}
+		assert.Nil(t, revisions[1].Replaced, "current revision should not be replaced")
+	}
+
+	// as of the first day the park document is about sausages, and it is matched since the second day
+	for asOf, matched := range map[string]bool{"2020-01-01T12:00:00Z": false, "2020-01-03": true} {
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(QueryHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/query?q=grand+teton+park&n=4&threshold=0.5&as_of="+asOf, nil))
+		assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+
+		resp := QueryResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, result := range resp.Results {
+			found = found || result.Name == "Grand Teton National Park.txt"
+		}
+		assert.Equal(t, matched, found, "park document matched as of %s", asOf)
+	}
+
+	for _, target := range []string{"/query?q=park&as_of=yesterday", "/query?q=park&as_of=2020-01-01&explain=true", "/query?q=park&as_of=2020-01-01&fusion=rrf"} {
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(QueryHandler).ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "incorrect status code for %s", target)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	serveFiles = false
+
+	testResponse(t, "national park", "1", "0.3", &QueryResponse{
+		Query: "national park",
+		Results: []Result{
+			{Name: "Grand Teton National Park.txt", Path: "", Similarity: "100"},
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(SuggestHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/suggest?prefix=Nation&n=2", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+
+	resp := SuggestResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"national park"}, resp.Queries, "incorrect queries")
+	assert.Len(t, resp.Terms, 2, "incorrect number of terms")
+	assert.Equal(t, "nation", resp.Terms[0][:6], "terms should complete the prefix")
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(SuggestHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/suggest?prefix=grand+tet", nil))
+	resp = SuggestResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "grand teton", resp.Terms[0], "only the last word should be completed")
+
+	// queries matching nothing are not suggested
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(QueryHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/query?q=xylophone+quartet", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(SuggestHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/suggest?prefix=xylophone", nil))
+	resp = SuggestResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, resp.Queries, "queries matching nothing should not be suggested")
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(SuggestHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/suggest?prefix=", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "incorrect status code")
+}
+
+func TestSuggestSharded(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	defer func(saved nlp.Corpus, by string, count int) {
+		corpus, shardBy, shardCount, shards = saved, by, count, nil
+	}(corpus, shardBy, shardCount)
+	corpus, shardBy, shardCount = nlp.NewCorpus(), "hash", 2
+	if err := corpus.Load("../books", regexp.MustCompile("\\.txt$")); err != nil {
+		t.Fatal(err)
+	}
+	if err := train("../books"); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(SuggestHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/suggest?prefix=grand+tet", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+	resp := SuggestResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "grand teton", resp.Terms[0], "terms should be completed from shards")
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(ShardedHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/topics", nil))
+	assert.Equal(t, http.StatusNotImplemented, rr.Code, "incorrect status code")
+}
+
+func TestQueryExplain(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(QueryHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/query?q=wild+weekend&n=1&explain=true", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+
+	resp := QueryResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, resp.Results, 1, "incorrect number of results")
+
+	explanation := resp.Results[0].Explanation
+	if assert.NotNil(t, explanation, "result should be explained") {
+		assert.Equal(t, 2, explanation.Document, "incorrect document")
+		assert.InDelta(t, 0.92, explanation.Similarity, 0.005, "explained similarity should match the result")
+		assert.Len(t, explanation.Terms, 2, "incorrect number of terms")
+		assert.Len(t, explanation.Components, 4, "incorrect number of components")
+	}
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(QueryHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/query?q=wild&explain=maybe", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "incorrect status code")
+}
+
+func TestQueryFusion(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(QueryHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/query?q=national+park&n=2&threshold=0&fusion=rrf&semantic=0.5&lexical=2", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+
+	resp := QueryResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, resp.Results, 2, "incorrect number of results")
+	assert.Equal(t, "Grand Teton National Park.txt", resp.Results[0].Name, "incorrect result")
+	assert.Equal(t, "100", resp.Results[0].Similarity, "result ranked first by both scores should fuse into 100")
+	for _, result := range resp.Results {
+		assert.NotEmpty(t, result.Semantic, "result should have semantic score")
+		assert.NotEmpty(t, result.Lexical, "result should have lexical score")
+	}
+
+	for _, query := range []string{"fusion=max", "fusion=sum&semantic=x", "fusion=sum&lexical=-1", "fusion=rrf&semantic=0&lexical=0"} {
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(QueryHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/query?q=wild&"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "incorrect status code for %s", query)
+	}
+}
+
+func TestQueryCache(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	queryCache = cache.NewLRU(10, time.Minute)
+	defer func() { queryCache = nil }()
+
+	want := &QueryResponse{
+		Query:   "wild weekend",
+		Results: []Result{{Name: "Grand Teton National Park.txt", Path: "", Similarity: "92"}},
+	}
+	testResponse(t, "wild weekend", "1", "0.3", want)
+	testResponse(t, "wild weekend", "1", "0.3", want)
+	want.Query = "Wild  Weekend"
+	testResponse(t, "Wild  Weekend", "1", "0.3", want)
+	testResponse(t, "wild weekend", "2", "0.3", nil)
+
+	assert.Equal(t, cache.Stats{Size: 2, Hits: 2, Misses: 2}, queryCache.Stats(), "incorrect cache stats")
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(CacheHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/cache", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+
+	// changing the model drops cached responses
+	if err := model.LoadSynonyms(strings.NewReader("")); err != nil {
+		t.Fatal(err)
+	}
+	want.Query = "wild weekend"
+	testResponse(t, "wild weekend", "1", "0.3", want)
+	assert.Equal(t, 1, queryCache.Stats().Purges, "cache should be purged")
+	assert.Equal(t, 3, queryCache.Stats().Misses, "query should not hit the cache")
+}
+
+func TestStaticArchiveMember(t *testing.T) {
+	dir, err := ioutil.TempDir("", "static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := os.Create(filepath.Join(dir, "bundle.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	w, err := z.Create("dir/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("geysers of yellowstone"))
+	z.Close()
+	f.Close()
+	if err := ioutil.WriteFile(filepath.Join(dir, "plain.txt"), []byte("grizzly bears"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := StaticHandler(dir)
+	for target, want := range map[string]string{"/static/bundle.zip!/dir/file.txt": "geysers of yellowstone", "/static/plain.txt": "grizzly bears"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+		assert.Equal(t, want, rr.Body.String(), "incorrect content")
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/static/bundle.zip!/missing.txt", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "incorrect status code")
+}
+
+func TestStaticRevision(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	dir, err := ioutil.TempDir("", "static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "design.txt"), []byte("geysers of yellowstone"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add("design.txt"); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := worktree.Commit("Add design", &git.CommitOptions{Author: &object.Signature{Name: "Jane", When: time.Now()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := corpus
+	defer func() {
+		corpus, gitRepository = saved, false
+	}()
+	corpus, gitRepository = nlp.NewCorpus(), true
+	if err := corpus.LoadRepository(dir, "", false, nlp.Filter{Pattern: regexp.MustCompile("\\.txt$")}); err != nil {
+		t.Fatal(err)
+	}
+	corpus.Release()
+
+	// revisions have no files, they are read back from the repository
+	handler := StaticHandler(dir)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/static/design.txt@"+hash.String()[:7], nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "incorrect status code")
+	assert.Equal(t, "geysers of yellowstone", rr.Body.String(), "incorrect content")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/static/design.txt@0000000", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "unknown revisions should not be found")
+}
+
+func TestServeWatcherChecks(t *testing.T) {
+	defer func() {
+		shardBy, watcherEnabled, gitRepository = "", false, false
+	}()
+
+	// flags are checked before the folder is loaded
+	for _, args := range [][]string{{"--shard-by", "hash", "-w"}, {"--git", "-w"}} {
+		err := NewApp().Run(append([]string{"qdox", "serve"}, append(args, "missing/")...))
+		if assert.Error(t, err, "%v should be rejected", args) {
+			assert.Contains(t, err.Error(), "watcher does not support", "incorrect error for %v", args)
+		}
+		shardBy, watcherEnabled, gitRepository = "", false, false
+	}
 }
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/stormcrows/qdox/pkg/ann"
+	"github.com/stormcrows/qdox/pkg/nlp"
+)
+
+// searcher answers queries about the trained corpus, either with the model or with shards of the corpus
+type searcher interface {
+	Query(q string, n int, threshold float64) nlp.QueryResult
+	QueryWith(q string, n int, threshold float64, fusion nlp.Fusion) nlp.QueryResult
+	Snippet(doc int, q string, words int) (string, error)
+	Explain(q string, doc int) (nlp.Explanation, error)
+	Complete(prefix string, n int) []string
+	Generation() uint64
+}
+
+// currentSearcher returns shards of the corpus when it is sharded, or the model otherwise
+func currentSearcher() searcher {
+	if shards != nil {
+		return shards
+	}
+	return model
+}
+
+// configureModel applies query and training flags to the model
+func configureModel(m *nlp.Model) error {
+	m.Expansion = expansion
+	m.ANN = ann.Config{M: annM, EfConstruction: annEfConstruction, EfSearch: annEfSearch, MinVectors: annMinDocuments}
+	m.Collapse = collapse
+	m.AutoCorrect = autoCorrect
+	m.Fusion = nlp.Fusion{Method: fusion, Semantic: semanticWeight, Lexical: lexicalWeight}
+	m.Reduction = nlp.Reduction{Randomised: randomised, Oversampling: oversampling, PowerIterations: powerIterations, Sample: trainingSample}
+	return m.Fusion.Validate()
+}
+
+// train trains the model on the loaded corpus, or splits the corpus into shards trained in parallel when asked to
+func train(folder string) error {
+	shards = nil
+	if shardBy == "" {
+		if err := configureModel(model); err != nil {
+			return err
+		}
+		return model.Train(&corpus)
+	}
+
+	var key func(path string) string
+	switch shardBy {
+	case "subfolder":
+		key = nlp.BySubfolder(folder)
+	case "hash":
+		if shardCount < 1 {
+			return fmt.Errorf("number of shards should be positive")
+		}
+		key = nlp.ByHash(shardCount)
+	default:
+		return fmt.Errorf("unknown sharding %q", shardBy)
+	}
+
+	var err error
+	sharded := nlp.NewSharded(&corpus, key, func() *nlp.Model {
+		m := nlp.NewLSIModel()
+		if e := configureModel(m); e != nil {
+			err = e
+		}
+		return m
+	})
+	if err != nil {
+		return err
+	}
+	if err := sharded.Train(); err != nil {
+		return err
+	}
+	shards = sharded
+	return nil
+}
+
+// trainingStats describes training of the model, or of every shard
+func trainingStats() []string {
+	if shards == nil {
+		return []string{model.Stats().String()}
+	}
+	lines := make([]string, len(shards.Shards))
+	for i, shard := range shards.Shards {
+		lines[i] = fmt.Sprintf("shard %s: %s", shard.Name, shard.Model.Stats())
+	}
+	return lines
+}
+
+// loadSynonyms reads synonyms file into the model, or models of all shards, if given
+func loadSynonyms(file string) error {
+	if shards == nil {
+		return loadSynonymsInto(file, model)
+	}
+	models := make([]*nlp.Model, len(shards.Shards))
+	for i, shard := range shards.Shards {
+		models[i] = shard.Model
+	}
+	return loadSynonymsInto(file, models...)
+}
+
+// loadSynonymsInto reads synonyms file into given models, if given
+func loadSynonymsInto(file string, models ...*nlp.Model) error {
+	if file == "" {
+		return nil
+	}
+	rules, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	for _, m := range models {
+		if err := m.LoadSynonyms(bytes.NewReader(rules)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
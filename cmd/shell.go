@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/stormcrows/qdox/pkg/nlp"
+	"github.com/urfave/cli"
+)
+
+const shellHelp = `type a query to search documents, or one of the commands:
+  :n [number]         maximum number of results to return
+  :t [threshold]      required minimum similarity per document
+  :pattern [regexp]   reloads documents matching the pattern and retrains the model
+  :open [result]      opens given result of the last query
+  :similar [result]   shows documents similar to given result of the last query
+  :history            lists previous queries and commands
+  ![number]           repeats given entry of the history
+  :help               shows this help
+  :quit               exits the shell
+`
+
+// Shell command trains the model once and then answers queries typed interactively
+var Shell = cli.Command{
+	Name:  "shell",
+	Usage: "qdox shell [command options] [folder]",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:        "pattern, P",
+			Usage:       "only parse files matching regular expression",
+			Destination: &pattern,
+			Value:       "\\.txt$",
+		},
+		cli.IntFlag{
+			Name:        "n",
+			Usage:       "maximum number of results to return",
+			Destination: &n,
+			Value:       5,
+		},
+		cli.Float64Flag{
+			Name:        "threshold, t",
+			Usage:       "required minimum similarity per document",
+			Destination: &threshold,
+			Value:       0.3,
+		},
+		cli.BoolFlag{
+			Name:        "autocorrect, a",
+			Usage:       "reruns queries matching nothing with their suggested spelling",
+			Destination: &autoCorrect,
+		},
+		cli.StringFlag{
+			Name:        "synonyms",
+			Usage:       "expands queries with Solr-style synonyms from given file",
+			Destination: &synonymsFile,
+		},
+		cli.IntFlag{
+			Name:        "expand, e",
+			Usage:       "expands queries with given number of closest terms per query term",
+			Destination: &expansion,
+		},
+	}, loadingFlags...),
+	Action: func(c *cli.Context) {
+		if len(c.Args()) < 1 {
+			fatal(fmt.Errorf("please provide source folder"))
+		}
+
+		patternr = regexp.MustCompile(pattern)
+		fatal(setFilter(c))
+		folder := path.Clean(c.Args().Get(0))
+
+		fatal(loadCorpus(&corpus, folder, filter))
+		fatal(configureModel(model))
+		fatal(model.Train(&corpus))
+		fatal(loadSynonyms(synonymsFile))
+
+		s := &shell{w: c.App.Writer, folder: folder}
+		fmt.Fprintf(s.w, "%d documents loaded, type :help for commands\n", corpus.Len())
+		s.run(stdin)
+	},
+}
+
+// shell keeps state of the interactive session
+type shell struct {
+	w       io.Writer
+	folder  string
+	history []string
+	last    nlp.QueryResult
+}
+
+// run reads lines until the input ends or user quits
+func (s *shell) run(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(s.w, "qdox> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(s.w)
+			return
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			i, err := strconv.Atoi(line[1:])
+			if err != nil || i < 1 || i > len(s.history) {
+				fmt.Fprintf(s.w, "error: no history entry %q\n", line[1:])
+				continue
+			}
+			line = s.history[i-1]
+			fmt.Fprintln(s.w, line)
+		}
+
+		if line != ":history" {
+			s.history = append(s.history, line)
+		}
+		if quit := s.execute(line); quit {
+			return
+		}
+	}
+}
+
+// execute runs a single command or query, returning true when the shell should exit
+func (s *shell) execute(line string) bool {
+	fields := strings.Fields(line)
+	arg := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+
+	switch fields[0] {
+	case ":quit", ":q", ":exit":
+		return true
+	case ":help":
+		fmt.Fprint(s.w, shellHelp)
+	case ":history":
+		for i, entry := range s.history {
+			fmt.Fprintf(s.w, "%d %s\n", i+1, entry)
+		}
+	case ":n":
+		v, err := strconv.Atoi(arg)
+		if err != nil || v < 1 {
+			fmt.Fprintln(s.w, "error: n should be a positive integer")
+			break
+		}
+		n = v
+		fmt.Fprintf(s.w, "n = %d\n", n)
+	case ":t", ":threshold":
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil || v < 0.0 {
+			fmt.Fprintln(s.w, "error: threshold should be a non-negative float number")
+			break
+		}
+		threshold = v
+		fmt.Fprintf(s.w, "threshold = %.2f\n", threshold)
+	case ":pattern", ":P":
+		r, err := regexp.Compile(arg)
+		if err != nil {
+			fmt.Fprintf(s.w, "error: %s\n", err)
+			break
+		}
+		f := filter
+		f.Pattern = r
+		// documents are loaded and trained aside, replacing those of the session only once both succeed,
+		// and the store keeps documents of the pattern the shell started with
+		next := nlp.NewCorpus()
+		if err := loadFiles(&next, s.folder, f); err != nil {
+			fmt.Fprintf(s.w, "error: %s\n", err)
+			break
+		}
+		reportSkipped(&next)
+		if next.Len() == 0 {
+			fmt.Fprintf(s.w, "error: no documents match %q\n", arg)
+			break
+		}
+		m := nlp.NewLSIModel()
+		if err := configureModel(m); err != nil {
+			fmt.Fprintf(s.w, "error: %s\n", err)
+			break
+		}
+		if err := m.Train(&next); err != nil {
+			fmt.Fprintf(s.w, "error: %s\n", err)
+			break
+		}
+		if err := loadSynonymsInto(synonymsFile, m); err != nil {
+			fmt.Fprintf(s.w, "error: %s\n", err)
+			break
+		}
+		if documentStore != nil {
+			next.UseStore(documentStore)
+		}
+		corpus, model = next, m
+		model.Corpus = &corpus
+		pattern, patternr, filter = arg, r, f
+		s.last = nlp.QueryResult{}
+		fmt.Fprintf(s.w, "%d documents loaded\n", corpus.Len())
+	case ":open":
+		doc, err := s.result(arg)
+		if err != nil {
+			fmt.Fprintf(s.w, "error: %s\n", err)
+			break
+		}
+		if err := openDocument(corpus.GetPath(doc)); err != nil {
+			fmt.Fprintf(s.w, "error: %s\n", err)
+		}
+	case ":similar":
+		doc, err := s.result(arg)
+		if err != nil {
+			fmt.Fprintf(s.w, "error: %s\n", err)
+			break
+		}
+		s.print(model.Similar(doc, n, threshold))
+	default:
+		if strings.HasPrefix(fields[0], ":") {
+			fmt.Fprintf(s.w, "error: unknown command %s, type :help for commands\n", fields[0])
+			break
+		}
+		s.print(model.Query(line, n, threshold))
+	}
+
+	return false
+}
+
+// result returns document of the last query's result with given number
+func (s *shell) result(arg string) (int, error) {
+	i, err := strconv.Atoi(arg)
+	if err != nil || i < 1 || i > len(s.last.Matched) {
+		return 0, fmt.Errorf("no result %q of the last query", arg)
+	}
+	return s.last.Matched[i-1], nil
+}
+
+func (s *shell) print(result nlp.QueryResult) {
+	if result.Err != nil {
+		fmt.Fprintf(s.w, "error: %s\n", result.Err)
+		return
+	}
+	s.last = result
+
+	if result.Corrected {
+		fmt.Fprintf(s.w, "showing results for: %q\n", result.Suggestion)
+	} else if result.Suggestion != "" {
+		fmt.Fprintf(s.w, "did you mean: %q\n", result.Suggestion)
+	}
+	if len(result.Matched) == 0 {
+		fmt.Fprintln(s.w, "no matches found")
+	}
+	for i, v := range result.Matched {
+		fmt.Fprintf(s.w, "%d. %.0f%% %q\n", i+1, result.Similarities[i]*100.0, corpus.GetPath(v))
+	}
+}
+
+// openDocument opens the file with the default application of the system
+func openDocument(file string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", file).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", file).Start()
+	default:
+		return exec.Command("xdg-open", file).Start()
+	}
+}
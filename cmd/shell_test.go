@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShell(t *testing.T) {
+	app := NewApp()
+	buf := new(bytes.Buffer)
+	app.Writer = buf
+	stdin = strings.NewReader(":n 1\nwild weekend\n:similar 1\n:t x\n:history\n!2\n:quit\nknight\n")
+	app.Run([]string{"qdox", "shell", "../books/"})
+
+	out := buf.String()
+	assert.Contains(t, out, "4 documents loaded", "documents should be loaded once")
+	assert.Contains(t, out, "n = 1\n", "n should be changed")
+	assert.Contains(t, out, "1. 92% \"../books/Grand Teton National Park.txt\"\n", "query should be answered")
+	assert.Contains(t, out, "error: threshold should be a non-negative float number\n", "invalid threshold should be reported")
+	assert.Contains(t, out, "1 :n 1\n2 wild weekend\n3 :similar 1\n4 :t x\n", "history should be listed")
+	assert.Equal(t, 2, strings.Count(out, "1. 92% \"../books/Grand Teton National Park.txt\"\n"), "history entry should be repeated")
+	assert.NotContains(t, out, "knight", "shell should exit on :quit")
+}
+
+func TestShellPatternMatchingNothing(t *testing.T) {
+	app := NewApp()
+	buf := new(bytes.Buffer)
+	app.Writer = buf
+	stdin = strings.NewReader("wild weekend\n:pattern \\.none$\n:similar 1\nwild weekend\n:quit\n")
+	app.Run([]string{"qdox", "shell", "../books/"})
+
+	out := buf.String()
+	assert.Contains(t, out, "error: no documents match \"\\\\.none$\"\n", "pattern matching nothing should be reported")
+	assert.Equal(t, 2, strings.Count(out, "1. 92% \"../books/Grand Teton National Park.txt\"\n"), "documents of the session should be kept")
+	assert.NotContains(t, out, "no result", "results of the last query should be kept")
+}
+
+func TestShellPatternKeepsSynonyms(t *testing.T) {
+	f, err := ioutil.TempFile("", "synonyms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("getaway => wild weekend\n")
+	f.Close()
+	defer func() { synonymsFile = "" }()
+
+	app := NewApp()
+	buf := new(bytes.Buffer)
+	app.Writer = buf
+	stdin = strings.NewReader("getaway\n:pattern \\.txt$\ngetaway\n:quit\n")
+	app.Run([]string{"qdox", "shell", "--synonyms", f.Name(), "../books/"})
+
+	out := buf.String()
+	assert.Contains(t, out, "4 documents loaded\n", "documents should be reloaded")
+	assert.Equal(t, 2, strings.Count(out, "1. 92% \"../books/Grand Teton National Park.txt\"\n"), "retrained model should expand queries with synonyms")
+}
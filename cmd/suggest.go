@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SuggestResponse is JSON response to /suggest requests
+type SuggestResponse struct {
+	Prefix  string
+	Queries []string
+	Terms   []string
+}
+
+// SuggestHandler completes the prefix with past popular queries and vocabulary terms, responding with JSON
+func SuggestHandler(w http.ResponseWriter, r *http.Request) {
+	// args
+	var err error
+	args := r.URL.Query()
+
+	prefix := args.Get("prefix")
+	if strings.TrimSpace(prefix) == "" {
+		respond(http.StatusBadRequest, "prefix should be a non empty string", w)
+		return
+	}
+
+	n := 5
+	if args.Get("n") != "" {
+		n, err = strconv.Atoi(args.Get("n"))
+		if err != nil || n < 1 {
+			respond(http.StatusBadRequest, "n should be a positive integer", w)
+			return
+		}
+	}
+
+	// completions
+	resp := SuggestResponse{prefix, make([]string, 0), make([]string, 0)}
+
+	for _, c := range popularQueries.Complete(normaliseQuery(prefix), n) {
+		resp.Queries = append(resp.Queries, c.Text)
+	}
+
+	// only the word being typed is completed with terms, the rest of the prefix is kept as it is
+	if last := strings.LastIndexFunc(prefix, unicode.IsSpace); last < len(prefix)-1 {
+		head, word := prefix[:last+1], strings.ToLower(prefix[last+1:])
+		for _, term := range currentSearcher().Complete(word, n) {
+			resp.Terms = append(resp.Terms, head+term)
+		}
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		log.Println(fmt.Sprintf("json marshalling error: %s", err))
+		respond(http.StatusInternalServerError, "", w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	respond(http.StatusOK, string(body), w)
+}
+
+// normaliseQuery lowercases the query and collapses its whitespace, so the same queries are counted together
+func normaliseQuery(q string) string {
+	return strings.Join(strings.Fields(strings.ToLower(q)), " ")
+}
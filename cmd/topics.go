@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/stormcrows/qdox/pkg/nlp"
+	"github.com/urfave/cli"
+)
+
+// TopicResult describes a single LSI dimension by its top terms and most strongly loaded documents
+type TopicResult struct {
+	Terms     []string
+	Variance  string
+	Documents []Result
+}
+
+// TopicsResponse is JSON response to /topics requests
+type TopicsResponse struct {
+	Topics []TopicResult
+}
+
+// Topics command trains the model and lists concepts it has reduced documents to
+var Topics = cli.Command{
+	Name:  "topics",
+	Usage: "qdox topics [command options] [folder]",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:        "pattern, P",
+			Usage:       "only parse files matching regular expression",
+			Destination: &pattern,
+			Value:       "\\.txt$",
+		},
+		cli.IntFlag{
+			Name:        "terms",
+			Usage:       "number of highest weighted terms per topic",
+			Destination: &terms,
+			Value:       5,
+		},
+		cli.IntFlag{
+			Name:        "documents, d",
+			Usage:       "number of most strongly loaded documents per topic",
+			Destination: &documents,
+			Value:       3,
+		},
+		cli.StringFlag{
+			Name:        "format, f",
+			Usage:       "output format: table or json",
+			Destination: &format,
+			Value:       "table",
+		},
+	}, loadingFlags...),
+	Action: func(c *cli.Context) {
+		if len(c.Args()) < 1 {
+			fatal(fmt.Errorf("please provide source folder"))
+		}
+
+		patternr = regexp.MustCompile(pattern)
+		fatal(setFilter(c))
+		folder := path.Clean(c.Args().Get(0))
+
+		fatal(loadCorpus(&corpus, folder, filter))
+		fatal(model.Train(&corpus))
+
+		result, err := model.Topics(terms, documents)
+		fatal(err)
+
+		switch format {
+		case "json":
+			body, err := json.Marshal(newTopicsResponse(result))
+			fatal(err)
+			fmt.Fprintln(c.App.Writer, string(body))
+		case "table":
+			for i, topic := range result {
+				fmt.Fprintf(c.App.Writer, "#%d %.1f%% %s\n", i+1, topic.Variance*100.0, strings.Join(topic.Terms, ", "))
+				for j, v := range topic.Documents {
+					fmt.Fprintf(c.App.Writer, "  %.0f%% %q\n", topic.Loadings[j]*100.0, corpus.GetPath(v))
+				}
+			}
+		default:
+			fatal(fmt.Errorf("unknown format %q", format))
+		}
+	},
+}
+
+// TopicsHandler lists topics of the trained model and responds with JSON
+func TopicsHandler(w http.ResponseWriter, r *http.Request) {
+	// args
+	var err error
+	args := r.URL.Query()
+
+	terms := 5
+	if args.Get("terms") != "" {
+		terms, err = strconv.Atoi(args.Get("terms"))
+		if err != nil || terms < 0 {
+			respond(http.StatusBadRequest, "terms should be a non-negative integer", w)
+			return
+		}
+	}
+
+	documents := 3
+	if args.Get("documents") != "" {
+		documents, err = strconv.Atoi(args.Get("documents"))
+		if err != nil || documents < 0 {
+			respond(http.StatusBadRequest, "documents should be a non-negative integer", w)
+			return
+		}
+	}
+
+	log.Println(fmt.Sprintf("ip=%s, topics terms=%d, documents=%d", r.RemoteAddr, terms, documents))
+
+	// nlp topics
+	result, err := model.Topics(terms, documents)
+	if err != nil {
+		log.Println(fmt.Sprintf("topics error: %s", err))
+		respond(http.StatusInternalServerError, "", w)
+		return
+	}
+
+	// response
+	body, err := json.Marshal(newTopicsResponse(result))
+	if err != nil {
+		log.Println(fmt.Sprintf("json marshalling error: %s", err))
+		respond(http.StatusInternalServerError, "", w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	respond(http.StatusOK, string(body), w)
+}
+
+func newTopicsResponse(topics []nlp.Topic) TopicsResponse {
+	resp := TopicsResponse{make([]TopicResult, len(topics))}
+	for i, topic := range topics {
+		resp.Topics[i] = TopicResult{topic.Terms, fmt.Sprintf("%.1f", topic.Variance*100.0), make([]Result, len(topic.Documents))}
+		for j, v := range topic.Documents {
+			resp.Topics[i].Documents[j] = newResult(v, topic.Loadings[j])
+		}
+	}
+	return resp
+}
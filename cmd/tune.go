@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/stormcrows/qdox/pkg/eval"
+	"github.com/stormcrows/qdox/pkg/nlp"
+	"github.com/urfave/cli"
+)
+
+// TuneResult holds measures of the model trained and queried with a single configuration
+type TuneResult struct {
+	Rank      int
+	StopWords string
+	Threshold float64
+	eval.Metrics
+}
+
+// Tune command evaluates models across a grid of configurations and reports the best one for the corpus
+var Tune = cli.Command{
+	Name:  "tune",
+	Usage: "qdox tune [command options] [folder] [judgements]",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:        "pattern, P",
+			Usage:       "only parse files matching regular expression",
+			Destination: &pattern,
+			Value:       "\\.txt$",
+		},
+		cli.IntFlag{
+			Name:        "k",
+			Usage:       "number of results considered per query",
+			Destination: &cutoff,
+			Value:       10,
+		},
+		cli.StringFlag{
+			Name:        "queries, q",
+			Usage:       "file of \"qid query text\" lines, required by TREC qrels judgements",
+			Destination: &queries,
+		},
+		cli.StringFlag{
+			Name:        "ranks, r",
+			Usage:       "comma separated SVD ranks to try",
+			Destination: &ranks,
+			Value:       "2,4,8,16",
+		},
+		cli.StringFlag{
+			Name:        "stop-words, s",
+			Usage:       "comma separated stop words to try: default, none or path to a file of words",
+			Destination: &stopWordSets,
+			Value:       "default,none",
+		},
+		cli.StringFlag{
+			Name:        "thresholds, t",
+			Usage:       "comma separated similarity thresholds to try",
+			Destination: &thresholds,
+			Value:       "0.0,0.1,0.2,0.3,0.4,0.5",
+		},
+		cli.StringFlag{
+			Name:        "metric, m",
+			Usage:       "metric to maximise: p, r, map, mrr or ndcg",
+			Destination: &metric,
+			Value:       "map",
+		},
+		cli.StringFlag{
+			Name:        "output, o",
+			Usage:       "saves the best configuration as JSON to given file",
+			Destination: &output,
+		},
+	}, loadingFlags...),
+	Action: func(c *cli.Context) {
+		if len(c.Args()) < 2 {
+			fatal(fmt.Errorf("please provide source folder and judgements file"))
+		}
+
+		patternr = regexp.MustCompile(pattern)
+		fatal(setFilter(c))
+		folder := path.Clean(c.Args().Get(0))
+
+		judgements, err := loadJudgements(c.Args().Get(1), queries)
+		fatal(err)
+		judgements, _ = measurable(judgements)
+
+		rankGrid, err := parseInts(ranks)
+		fatal(err)
+		thresholdGrid, err := parseFloats(thresholds)
+		fatal(err)
+		_, err = metricValue(eval.Metrics{}, metric)
+		fatal(err)
+
+		results := make([]TuneResult, 0)
+		for _, set := range strings.Split(stopWordSets, ",") {
+			set = strings.TrimSpace(set)
+			words, err := loadStopWords(set)
+			fatal(err)
+
+			docs := nlp.NewCorpus()
+			fatal(loadFiles(&docs, folder, filter))
+
+			// ranks above the number of terms or documents reduce to the same model as the rank of the corpus
+			corpusRank := docs.Len()
+			if terms := nlp.NewLSIModelWith(1, words...).Terms(&docs); terms < corpusRank {
+				corpusRank = terms
+			}
+
+			for _, rank := range rankGrid {
+				if rank > corpusRank {
+					fmt.Fprintf(os.Stderr, "skipping rank %d above rank %d of the corpus with %s stop words\n", rank, corpusRank, set)
+					continue
+				}
+
+				// training releases contents of the corpus, which the next ranks need
+				trained := docs.Copy()
+				m := nlp.NewLSIModelWith(rank, words...)
+				m.MaxKeywords = 0
+				fatal(m.Train(&trained))
+
+				for _, t := range thresholdGrid {
+					metrics := evaluate(m, folder, judgements, cutoff, t)
+					results = append(results, TuneResult{rank, set, t, eval.Mean(metrics)})
+				}
+			}
+		}
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Rank < results[j].Rank })
+
+		best := 0
+		for i, r := range results {
+			value, _ := metricValue(r.Metrics, metric)
+			bestValue, _ := metricValue(results[best].Metrics, metric)
+			if value > bestValue {
+				best = i
+			}
+		}
+
+		w := tabwriter.NewWriter(c.App.Writer, 0, 8, 2, ' ', 0)
+		fmt.Fprintf(w, "rank\tstop words\tthreshold\tP@%d\tR@%d\tMAP\tMRR\tnDCG\n", cutoff, cutoff)
+		for _, r := range results {
+			fmt.Fprintf(w, "%d\t%s\t%.2f\t%s\n", r.Rank, r.StopWords, r.Threshold, formatMetrics(r.Metrics))
+		}
+		w.Flush()
+
+		if len(results) == 0 {
+			return
+		}
+		r := results[best]
+		fmt.Fprintf(c.App.Writer, "best %s: rank=%d stop-words=%s threshold=%.2f\n", metric, r.Rank, r.StopWords, r.Threshold)
+
+		if output != "" {
+			body, err := json.MarshalIndent(r, "", "  ")
+			fatal(err)
+			fatal(ioutil.WriteFile(output, body, 0644))
+		}
+	},
+}
+
+// loadStopWords resolves stop words setting into the list of words
+func loadStopWords(set string) ([]string, error) {
+	switch set {
+	case "default":
+		return nlp.StopWords(), nil
+	case "none":
+		return []string{}, nil
+	}
+	content, err := ioutil.ReadFile(set)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(strings.ToLower(string(content))), nil
+}
+
+func metricValue(m eval.Metrics, metric string) (float64, error) {
+	switch metric {
+	case "p":
+		return m.Precision, nil
+	case "r":
+		return m.Recall, nil
+	case "map":
+		return m.AP, nil
+	case "mrr":
+		return m.RR, nil
+	case "ndcg":
+		return m.NDCG, nil
+	}
+	return 0, fmt.Errorf("unknown metric %q", metric)
+}
+
+func parseInts(list string) ([]int, error) {
+	values := make([]int, 0)
+	for _, s := range strings.Split(list, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("%q should be a positive integer", s)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func parseFloats(list string) ([]float64, error) {
+	values := make([]float64, 0)
+	for _, s := range strings.Split(list, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || v < 0.0 {
+			return nil, fmt.Errorf("%q should be a non-negative float number", s)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
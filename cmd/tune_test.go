@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTune(t *testing.T) {
+	app := NewApp()
+	buf := new(bytes.Buffer)
+	app.Writer = buf
+	app.Run([]string{"qdox", "tune", "../books/", "../docs/books.jsonl", "-t", "0.0,0.3"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	// header, ranks 2 and 4 with default and no stop words at two thresholds, and the best one,
+	// ranks 8 and 16 being above the rank of the corpus of 4 books
+	if assert.Len(t, lines, 10, "incorrect number of lines: %s", buf.String()) {
+		for _, line := range lines[1:9] {
+			rank := strings.Fields(line)[0]
+			assert.True(t, rank == "2" || rank == "4", "unexpected rank in %q", line)
+		}
+		assert.Equal(t, "best map: rank=4 stop-words=default threshold=0.00", lines[9], "incorrect best configuration")
+	}
+}
@@ -1,21 +1,81 @@
 package cmd
 
 import (
+	"io"
+	"os"
 	"regexp"
+	"time"
 
+	"github.com/stormcrows/qdox/pkg/cache"
 	"github.com/stormcrows/qdox/pkg/nlp"
+	"github.com/stormcrows/qdox/pkg/store"
+	"github.com/stormcrows/qdox/pkg/suggest"
 )
 
+// maxPopularQueries bounds number of past queries kept to suggest, evicting the least popular ones
+const maxPopularQueries = 10000
+
 var (
-	port           = 8080
-	corpus         = nlp.NewCorpus()
-	model          = nlp.NewLSIModel()
-	n              = 5
-	threshold      = 0.3
-	serveFiles     = false
-	interact       = false
-	watcherEnabled = false
-	interval       = int64(1000)
-	pattern        = "\\.txt$"
-	patternr       = regexp.MustCompile(pattern)
+	port              = 8080
+	corpus            = nlp.NewCorpus()
+	model             = nlp.NewLSIModel()
+	n                 = 5
+	threshold         = 0.3
+	serveFiles        = false
+	interact          = false
+	watcherEnabled    = false
+	interval          = int64(1000)
+	clusters          = 3
+	terms             = 5
+	documents         = 3
+	keywords          = 10
+	keyphrases        = false
+	cutoff            = 10
+	queries           = ""
+	ranks             = "2,4,8,16"
+	stopWordSets      = "default,none"
+	thresholds        = "0.0,0.1,0.2,0.3,0.4,0.5"
+	metric            = "map"
+	output            = ""
+	format            = "table"
+	collapse          = 0.0
+	synonymsFile      = ""
+	expansion         = 0
+	autoCorrect       = false
+	fusion            = ""
+	semanticWeight    = 1.0
+	lexicalWeight     = 1.0
+	annM              = 0
+	annEfConstruction = 200
+	annEfSearch       = 64
+	annMinDocuments   = 10000
+	cacheSize         = 1000
+	cacheTTL          = 5 * time.Minute
+	queryCache        *cache.LRU
+	shardBy           = ""
+	shardCount        = 4
+	shards            *nlp.Sharded
+	randomised        = false
+	oversampling      = 10
+	powerIterations   = 2
+	trainingSample    = 0
+	showTrainingStats = false
+	snippetWords      = 30
+	similarity        = 0.9
+	pattern           = "\\.txt$"
+	patternr          = regexp.MustCompile(pattern)
+	filter            = nlp.Filter{Pattern: patternr}
+	maxSize           = ""
+	loadHidden        = false
+	loadArchives      = false
+	gitRepository     = false
+	gitRef            = "HEAD"
+	gitHistory        = false
+	servedFolder      = ""
+	fallbackEncoding  = nlp.DefaultEncoding
+	normalisation     = "NFC"
+	storeFile         = ""
+	documentStore     *store.Store
+	popularQueries    = suggest.NewBoundedTrie(maxPopularQueries)
+	stdin             = io.Reader(os.Stdin)
 )
@@ -0,0 +1,336 @@
+package ann
+
+import (
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// Config tunes recall and latency of the index
+type Config struct {
+	// M is the number of neighbours linked per node on upper layers, twice as many on the bottom one, 0 disables the index
+	M int
+	// EfConstruction is the number of candidates considered while linking new nodes, trading build time for recall
+	EfConstruction int
+	// EfSearch is the number of candidates considered by searches, trading latency for recall
+	EfSearch int
+	// MinVectors is the number of vectors below which exact scan is faster and the index is not built
+	MinVectors int
+	// Seed makes levels of nodes, and so the whole graph, reproducible
+	Seed int64
+}
+
+// DefaultConfig returns parameters giving high recall on corpora of up to millions of documents
+func DefaultConfig() Config {
+	return Config{M: 16, EfConstruction: 200, EfSearch: 64, MinVectors: 10000}
+}
+
+// Neighbour is an indexed vector along with its cosine similarity to the searched one
+type Neighbour struct {
+	ID         int
+	Similarity float64
+}
+
+// HNSW is a hierarchical navigable small world graph of L2 normalised vectors,
+// finding approximate nearest neighbours by their dot product, safe for concurrent use
+type HNSW struct {
+	mu       sync.RWMutex
+	config   Config
+	vectors  [][]float64
+	links    [][][]int
+	entry    int
+	maxLevel int
+	rng      *rand.Rand
+}
+
+// Normalised returns the configuration indexes are built with, linking at least 2 neighbours
+// and considering at least as many candidates while linking
+func (c Config) Normalised() Config {
+	if c.M < 2 {
+		c.M = 2
+	}
+	if c.EfConstruction < c.M {
+		c.EfConstruction = c.M
+	}
+	return c
+}
+
+// NewHNSW returns an empty index
+func NewHNSW(config Config) *HNSW {
+	config = config.Normalised()
+	return &HNSW{config: config, entry: -1, rng: rand.New(rand.NewSource(config.Seed))}
+}
+
+// Len returns number of indexed vectors
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.vectors)
+}
+
+// Dims returns number of dimensions of indexed vectors, 0 if there are none
+func (h *HNSW) Dims() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.vectors) == 0 {
+		return 0
+	}
+	return len(h.vectors[0])
+}
+
+// Config returns parameters the index was built with
+func (h *HNSW) Config() Config {
+	return h.config
+}
+
+// Vector returns the indexed vector of given id
+func (h *HNSW) Vector(id int) []float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.vectors[id]
+}
+
+// Add indexes the L2 normalised vector, returning its id which is the number of vectors added before it
+func (h *HNSW) Add(v []float64) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	id := len(h.vectors)
+	level := int(-math.Log(1-h.rng.Float64()) / math.Log(float64(h.config.M)))
+	h.vectors = append(h.vectors, v)
+	h.links = append(h.links, make([][]int, level+1))
+
+	if h.entry < 0 {
+		h.entry, h.maxLevel = id, level
+		return id
+	}
+
+	// descend greedily through layers above the new node's level
+	entry := []Neighbour{{h.entry, dot(v, h.vectors[h.entry])}}
+	for l := h.maxLevel; l > level; l-- {
+		entry = h.searchLayer(v, entry, 1, l)
+	}
+
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(v, entry, h.config.EfConstruction, l)
+		limit := h.config.M
+		if l == 0 {
+			limit *= 2
+		}
+		for _, c := range closest(candidates, h.config.M) {
+			h.links[id][l] = append(h.links[id][l], c.ID)
+			h.links[c.ID][l] = append(h.links[c.ID][l], id)
+			if len(h.links[c.ID][l]) > limit {
+				h.prune(c.ID, l, limit)
+			}
+		}
+		entry = candidates
+	}
+
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = id, level
+	}
+	return id
+}
+
+// Search returns up to k indexed vectors most similar to the L2 normalised query, best first
+func (h *HNSW) Search(q []float64, k int) []Neighbour {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.entry < 0 || k < 1 {
+		return []Neighbour{}
+	}
+
+	entry := []Neighbour{{h.entry, dot(q, h.vectors[h.entry])}}
+	for l := h.maxLevel; l > 0; l-- {
+		entry = h.searchLayer(q, entry, 1, l)
+	}
+	ef := h.config.EfSearch
+	if ef < k {
+		ef = k
+	}
+	return closest(h.searchLayer(q, entry, ef, 0), k)
+}
+
+// searchLayer finds up to ef nodes of the layer closest to the query, starting from entry points
+func (h *HNSW) searchLayer(q []float64, entry []Neighbour, ef int, layer int) []Neighbour {
+	visited := make(map[int]bool, ef*4)
+	candidates := &queue{best: true}
+	results := &queue{}
+	for _, e := range entry {
+		visited[e.ID] = true
+		heap.Push(candidates, e)
+		heap.Push(results, e)
+	}
+	for results.Len() > ef {
+		heap.Pop(results)
+	}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(Neighbour)
+		if results.Len() >= ef && c.Similarity < results.items[0].Similarity {
+			break
+		}
+		for _, id := range h.links[c.ID][layer] {
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+			n := Neighbour{id, dot(q, h.vectors[id])}
+			if results.Len() < ef || n.Similarity > results.items[0].Similarity {
+				heap.Push(candidates, n)
+				heap.Push(results, n)
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	return results.items
+}
+
+// prune keeps only the limit of links of the node to its closest neighbours
+func (h *HNSW) prune(id int, layer int, limit int) {
+	neighbours := make([]Neighbour, len(h.links[id][layer]))
+	for i, n := range h.links[id][layer] {
+		neighbours[i] = Neighbour{n, dot(h.vectors[id], h.vectors[n])}
+	}
+	kept := closest(neighbours, limit)
+	h.links[id][layer] = h.links[id][layer][:0]
+	for _, n := range kept {
+		h.links[id][layer] = append(h.links[id][layer], n.ID)
+	}
+}
+
+// snapshot is the encoded form of the index
+type snapshot struct {
+	Config   Config
+	Vectors  [][]float64
+	Links    [][][]int
+	Entry    int
+	MaxLevel int
+}
+
+// Encode writes the index, so it can be persisted along with the model
+func (h *HNSW) Encode(w io.Writer) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return gob.NewEncoder(w).Encode(snapshot{h.config, h.vectors, h.links, h.entry, h.maxLevel})
+}
+
+// Decode reads the index written by Encode
+func Decode(r io.Reader) (*HNSW, error) {
+	s := snapshot{}
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	h := NewHNSW(s.Config)
+	h.vectors, h.links, h.entry, h.maxLevel = s.Vectors, s.Links, s.Entry, s.MaxLevel
+	// gob omits empty slices, restore links of nodes without neighbours on some of their layers
+	for i := range h.links {
+		for l := range h.links[i] {
+			if h.links[i][l] == nil {
+				h.links[i][l] = []int{}
+			}
+		}
+	}
+	h.rng = rand.New(rand.NewSource(s.Config.Seed + int64(len(h.vectors))))
+	return h, nil
+}
+
+// validate checks the entry point and every link refer to indexed nodes present on their layers,
+// so searches of the decoded index cannot go out of range
+func (s *snapshot) validate() error {
+	if len(s.Links) != len(s.Vectors) {
+		return fmt.Errorf("index has %d vectors but links of %d", len(s.Vectors), len(s.Links))
+	}
+	if len(s.Vectors) == 0 {
+		if s.Entry != -1 {
+			return fmt.Errorf("empty index has entry point %d", s.Entry)
+		}
+		return nil
+	}
+	if s.Entry < 0 || s.Entry >= len(s.Vectors) {
+		return fmt.Errorf("entry point %d out of range [0, %d)", s.Entry, len(s.Vectors))
+	}
+	if len(s.Links[s.Entry]) != s.MaxLevel+1 {
+		return fmt.Errorf("entry point %d has %d layers, expected %d", s.Entry, len(s.Links[s.Entry]), s.MaxLevel+1)
+	}
+	dims := len(s.Vectors[0])
+	for id, layers := range s.Links {
+		if len(s.Vectors[id]) != dims {
+			return fmt.Errorf("vector %d has %d dimensions, expected %d", id, len(s.Vectors[id]), dims)
+		}
+		if len(layers) == 0 || len(layers) > s.MaxLevel+1 {
+			return fmt.Errorf("node %d has %d layers out of range [1, %d]", id, len(layers), s.MaxLevel+1)
+		}
+		for l, links := range layers {
+			for _, n := range links {
+				if n < 0 || n >= len(s.Vectors) || len(s.Links[n]) <= l {
+					return fmt.Errorf("node %d links node %d missing from layer %d", id, n, l)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// closest returns up to k neighbours with highest similarities, best first
+func closest(neighbours []Neighbour, k int) []Neighbour {
+	sorted := append([]Neighbour(nil), neighbours...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[j].Similarity < sorted[i].Similarity })
+	if len(sorted) > k {
+		sorted = sorted[:k]
+	}
+	return sorted
+}
+
+func dot(a, b []float64) float64 {
+	s := 0.0
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// queue is a heap of neighbours, with the best one on top if best is set, or the worst one otherwise
+type queue struct {
+	items []Neighbour
+	best  bool
+}
+
+func (q *queue) Len() int { return len(q.items) }
+
+func (q *queue) Less(i, j int) bool {
+	if q.best {
+		return q.items[j].Similarity < q.items[i].Similarity
+	}
+	return q.items[i].Similarity < q.items[j].Similarity
+}
+
+func (q *queue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *queue) Push(x interface{}) { q.items = append(q.items, x.(Neighbour)) }
+
+func (q *queue) Pop() interface{} {
+	last := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return last
+}
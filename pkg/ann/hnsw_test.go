@@ -0,0 +1,126 @@
+package ann
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func randomVectors(n, dims int, seed int64) [][]float64 {
+	rng := rand.New(rand.NewSource(seed))
+	vectors := make([][]float64, n)
+	for i := range vectors {
+		v := make([]float64, dims)
+		norm := 0.0
+		for j := range v {
+			v[j] = rng.NormFloat64()
+			norm += v[j] * v[j]
+		}
+		for j := range v {
+			v[j] /= math.Sqrt(norm)
+		}
+		vectors[i] = v
+	}
+	return vectors
+}
+
+func exactSearch(vectors [][]float64, q []float64, k int) []int {
+	ids := make([]int, len(vectors))
+	for i := range ids {
+		ids[i] = i
+	}
+	sort.Slice(ids, func(i, j int) bool { return dot(q, vectors[ids[j]]) < dot(q, vectors[ids[i]]) })
+	return ids[:k]
+}
+
+func TestSearchRecall(t *testing.T) {
+	vectors := randomVectors(2000, 8, 1)
+	h := NewHNSW(Config{M: 8, EfConstruction: 100, EfSearch: 50, Seed: 1})
+	for i, v := range vectors {
+		if id := h.Add(v); id != i {
+			t.Fatalf("expected id %d, got: %d", i, id)
+		}
+	}
+	if h.Len() != len(vectors) {
+		t.Fatalf("expected %d vectors, got: %d", len(vectors), h.Len())
+	}
+
+	found, total := 0, 0
+	for _, q := range randomVectors(50, 8, 2) {
+		exact := make(map[int]bool)
+		for _, id := range exactSearch(vectors, q, 10) {
+			exact[id] = true
+		}
+		neighbours := h.Search(q, 10)
+		for i, n := range neighbours {
+			if exact[n.ID] {
+				found++
+			}
+			if i > 0 && n.Similarity > neighbours[i-1].Similarity {
+				t.Errorf("expected neighbours ordered by similarity, got: %v", neighbours)
+			}
+		}
+		total += len(exact)
+	}
+
+	if recall := float64(found) / float64(total); recall < 0.9 {
+		t.Errorf("expected recall of at least 0.9, got: %.2f", recall)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	h := NewHNSW(Config{M: 4, EfConstruction: 20, EfSearch: 10})
+	for _, v := range randomVectors(200, 4, 3) {
+		h.Add(v)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := h.Encode(buf); err != nil {
+		t.Fatalf("error encoding index %s", err.Error())
+	}
+	decoded, err := Decode(buf)
+	if err != nil {
+		t.Fatalf("error decoding index %s", err.Error())
+	}
+
+	for _, q := range randomVectors(10, 4, 4) {
+		if want, got := h.Search(q, 5), decoded.Search(q, 5); !reflect.DeepEqual(want, got) {
+			t.Errorf("expected decoded index to find %v, got: %v", want, got)
+		}
+	}
+	if got := NewHNSW(DefaultConfig()).Search(randomVectors(1, 4, 5)[0], 5); len(got) != 0 {
+		t.Errorf("expected no neighbours in empty index, got: %v", got)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	vectors := randomVectors(3, 2, 6)
+	for _, s := range []snapshot{
+		{Config: DefaultConfig(), Vectors: vectors, Links: [][][]int{{{1}}, {{0}}}, Entry: 0},
+		{Config: DefaultConfig(), Vectors: vectors, Links: [][][]int{{{1}}, {{0}}, {{3}}}, Entry: 0},
+		{Config: DefaultConfig(), Vectors: vectors, Links: [][][]int{{{1}}, {{0}}, {{-1}}}, Entry: 0},
+		{Config: DefaultConfig(), Vectors: vectors, Links: [][][]int{{{1}}, {{0}}, {{0}}}, Entry: 3},
+		{Config: DefaultConfig(), Vectors: vectors, Links: [][][]int{{{1}, {2}}, {{0}}, {{0}}}, Entry: 0, MaxLevel: 1},
+		{Config: DefaultConfig(), Vectors: vectors, Links: [][][]int{{{1}}, {{0}}, {{0}}}, Entry: 0, MaxLevel: 1},
+		{Config: DefaultConfig(), Entry: 0},
+	} {
+		buf := new(bytes.Buffer)
+		if err := gob.NewEncoder(buf).Encode(s); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Decode(buf); err == nil {
+			t.Errorf("expected error decoding invalid index %v", s)
+		}
+	}
+}
+
+func TestNormalisedConfig(t *testing.T) {
+	config := Config{M: 1, EfConstruction: 1}
+	if got := NewHNSW(config).Config(); got != config.Normalised() || got.M != 2 || got.EfConstruction != 2 {
+		t.Errorf("expected index to be built with normalised configuration, got: %v", got)
+	}
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU keeps up to size most recently used values for at most ttl, safe for concurrent use
+type LRU struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	version uint64
+	entries map[string]*list.Element
+	order   *list.List
+	stats   Stats
+	now     func() time.Time
+}
+
+// Stats counts lookups of the cache along with values dropped from it
+type Stats struct {
+	Size        int
+	Hits        int
+	Misses      int
+	Evictions   int
+	Expirations int
+	Purges      int
+}
+
+type entry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// NewLRU returns an empty cache of given size, with entries expiring after ttl unless it is 0
+func NewLRU(size int, ttl time.Duration) *LRU {
+	return &LRU{size: size, ttl: ttl, entries: make(map[string]*list.Element), order: list.New(), now: time.Now}
+}
+
+// Get returns the value cached for the key, unless it is missing or expired
+func (c *LRU) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	if c.ttl > 0 && c.now().After(e.Value.(*entry).expires) {
+		c.remove(e)
+		c.stats.Expirations++
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+	c.stats.Hits++
+	return e.Value.(*entry).value, true
+}
+
+// Add caches the value for the key, evicting the least recently used entry when the cache is full
+func (c *LRU) Add(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.size < 1 {
+		return
+	}
+
+	expires := c.now().Add(c.ttl)
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*entry).value, e.Value.(*entry).expires = value, expires
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key, value, expires})
+	if c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Sync drops all entries when the version of cached data differs from the one entries were added for
+func (c *LRU) Sync(version uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if version == c.version {
+		return
+	}
+	c.version = version
+	if c.order.Len() > 0 {
+		c.entries = make(map[string]*list.Element)
+		c.order.Init()
+		c.stats.Purges++
+	}
+}
+
+// Stats returns counts of lookups and dropped entries since the cache was created
+func (c *LRU) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
+
+func (c *LRU) remove(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*entry).key)
+}
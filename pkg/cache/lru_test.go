@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	c := NewLRU(2, 0)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("expected cached a, got: %v", v)
+	}
+
+	// b is the least recently used now
+	c.Add("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("expected b to be evicted")
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Errorf("expected cached c, got: %v", v)
+	}
+
+	c.Add("a", 4)
+	if v, _ := c.Get("a"); v != 4 {
+		t.Errorf("expected updated a, got: %v", v)
+	}
+
+	want := Stats{Size: 2, Hits: 3, Misses: 1, Evictions: 1}
+	if got := c.Stats(); got != want {
+		t.Errorf("expected stats %v, got: %v", want, got)
+	}
+
+	if NewLRU(0, 0).Add("a", 1); NewLRU(0, 0).Stats().Size != 0 {
+		t.Errorf("expected cache of size 0 to keep nothing")
+	}
+}
+
+func TestLRUExpiration(t *testing.T) {
+	now := time.Now()
+	c := NewLRU(10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Add("a", 1)
+	now = now.Add(30 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("expected a not to expire yet")
+	}
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("expected a to expire")
+	}
+	if stats := c.Stats(); stats.Expirations != 1 || stats.Size != 0 {
+		t.Errorf("expected single expiration, got: %v", stats)
+	}
+}
+
+func TestLRUSync(t *testing.T) {
+	c := NewLRU(10, 0)
+	c.Sync(1)
+	c.Add("a", 1)
+	c.Sync(1)
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("expected a to survive sync of the same version")
+	}
+	c.Sync(2)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("expected a to be dropped by sync of another version")
+	}
+	if stats := c.Stats(); stats.Purges != 1 {
+		t.Errorf("expected single purge, got: %v", stats)
+	}
+}
@@ -0,0 +1,94 @@
+package eval
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	relevance := map[string]float64{"a": 1, "c": 1, "d": 0}
+	m := Evaluate([]string{"b", "a", "d", "c"}, relevance, 3)
+
+	expect(t, "precision", 1.0/3.0, m.Precision)
+	expect(t, "recall", 0.5, m.Recall)
+	expect(t, "average precision", 0.25, m.AP)
+	expect(t, "reciprocal rank", 0.5, m.RR)
+	expect(t, "ndcg", (1/math.Log2(3))/(1+1/math.Log2(3)), m.NDCG)
+
+	perfect := Evaluate([]string{"a", "c"}, relevance, 10)
+	expect(t, "perfect ndcg", 1.0, perfect.NDCG)
+	expect(t, "perfect average precision", 1.0, perfect.AP)
+
+	none := Evaluate([]string{"a"}, map[string]float64{}, 10)
+	expect(t, "no relevant documents", 0.0, none.NDCG)
+}
+
+func TestMean(t *testing.T) {
+	m := Mean([]Metrics{{Precision: 1, RR: 1}, {Precision: 0, RR: 0.5}})
+	expect(t, "mean precision", 0.5, m.Precision)
+	expect(t, "mean reciprocal rank", 0.75, m.RR)
+}
+
+func TestLoadJSONL(t *testing.T) {
+	judgements, err := LoadJSONL(strings.NewReader(`{"id": "q1", "query": "wild weekend", "relevant": ["books/park.txt"]}
+
+{"query": "sausages", "relevant": {"red book.txt": 2, "park.txt": 0}}
+`))
+	if err != nil {
+		t.Fatalf("error loading judgements: %s", err)
+	}
+	if len(judgements) != 2 {
+		t.Fatalf("expected 2 judgements, got: %d", len(judgements))
+	}
+	if judgements[0].Relevance["books/park.txt"] != 1 {
+		t.Errorf("expected listed document to be relevant, got: %v", judgements[0].Relevance)
+	}
+	if judgements[1].ID != "2" || judgements[1].Relevance["red book.txt"] != 2 {
+		t.Errorf("expected graded judgement with generated id, got: %v", judgements[1])
+	}
+
+	if _, err := LoadJSONL(strings.NewReader(`{"id": "q1", "relevant": []}`)); err == nil {
+		t.Errorf("expected error for missing query")
+	}
+}
+
+func TestLoadQrels(t *testing.T) {
+	judgements, err := LoadQrels(
+		strings.NewReader("q1 0 park.txt 1\nq1 0 ./books/red.txt 0\nq2 0 red.txt 2\n"),
+		strings.NewReader("q1 wild weekend\nq2 sausage recipes\n"),
+	)
+	if err != nil {
+		t.Fatalf("error loading judgements: %s", err)
+	}
+	if len(judgements) != 2 || judgements[0].Query != "wild weekend" {
+		t.Fatalf("expected 2 judgements, got: %v", judgements)
+	}
+	if _, ok := judgements[0].Relevance["books/red.txt"]; !ok {
+		t.Errorf("expected document id to be normalised, got: %v", judgements[0].Relevance)
+	}
+
+	if _, err := LoadQrels(strings.NewReader("q3 0 a.txt 1\n"), strings.NewReader("q1 wild\n")); err == nil {
+		t.Errorf("expected error for unknown query id")
+	}
+}
+
+func TestJudged(t *testing.T) {
+	judged, skipped := Judged([]Judgement{
+		{"q1", "wild", map[string]float64{"park.txt": 1}},
+		{"q2", "tame", map[string]float64{"park.txt": 0}},
+		{"q3", "none", map[string]float64{}},
+	})
+	if len(judged) != 1 || judged[0].ID != "q1" {
+		t.Errorf("expected only q1 to be judged, got: %v", judged)
+	}
+	if len(skipped) != 2 || skipped[0] != "q2" || skipped[1] != "q3" {
+		t.Errorf("expected q2 and q3 to be skipped, got: %v", skipped)
+	}
+}
+
+func expect(t *testing.T, name string, want float64, got float64) {
+	if math.Abs(want-got) > 1e-9 {
+		t.Errorf("expected %s to be %f, got: %f", name, want, got)
+	}
+}
@@ -0,0 +1,141 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Judgement holds a query along with graded relevance of documents judged for it
+type Judgement struct {
+	ID        string
+	Query     string
+	Relevance map[string]float64
+}
+
+type jsonJudgement struct {
+	ID       string          `json:"id"`
+	Query    string          `json:"query"`
+	Relevant json.RawMessage `json:"relevant"`
+}
+
+// LoadJSONL reads judgements, one JSON object per line, e.g.:
+//
+//	{"id": "q1", "query": "wild weekend", "relevant": ["Grand Teton National Park.txt"]}
+//	{"id": "q2", "query": "sausage recipes", "relevant": {"Butchers Packers and Sausage Makers Red Book.txt": 2}}
+func LoadJSONL(r io.Reader) ([]Judgement, error) {
+	judgements := make([]Judgement, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var j jsonJudgement
+		if err := json.Unmarshal([]byte(text), &j); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		if j.Query == "" {
+			return nil, fmt.Errorf("line %d: query should be a non empty string", line)
+		}
+		if j.ID == "" {
+			j.ID = strconv.Itoa(len(judgements) + 1)
+		}
+
+		judgement := Judgement{j.ID, j.Query, make(map[string]float64)}
+
+		var list []string
+		var graded map[string]float64
+		if err := json.Unmarshal(j.Relevant, &list); err == nil {
+			for _, doc := range list {
+				judgement.Relevance[DocumentID(doc)] = 1
+			}
+		} else if err := json.Unmarshal(j.Relevant, &graded); err == nil {
+			for doc, grade := range graded {
+				judgement.Relevance[DocumentID(doc)] = grade
+			}
+		} else {
+			return nil, fmt.Errorf("line %d: relevant should be a list of documents or a map of their grades", line)
+		}
+
+		judgements = append(judgements, judgement)
+	}
+
+	return judgements, scanner.Err()
+}
+
+// LoadQrels reads TREC relevance judgements ("qid iteration docno relevance" lines)
+// along with queries ("qid query text" lines) they refer to
+func LoadQrels(qrels io.Reader, queries io.Reader) ([]Judgement, error) {
+	judgements := make([]Judgement, 0)
+	index := make(map[string]int)
+
+	scanner := bufio.NewScanner(queries)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("queries line %d: expected query id followed by query text", line)
+		}
+		index[fields[0]] = len(judgements)
+		judgements = append(judgements, Judgement{fields[0], strings.Join(fields[1:], " "), make(map[string]float64)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	scanner = bufio.NewScanner(qrels)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("qrels line %d: expected qid, iteration, docno and relevance", line)
+		}
+		i, ok := index[fields[0]]
+		if !ok {
+			return nil, fmt.Errorf("qrels line %d: unknown query id %q", line, fields[0])
+		}
+		grade, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("qrels line %d: relevance should be a number", line)
+		}
+		judgements[i].Relevance[DocumentID(fields[2])] = grade
+	}
+
+	return judgements, scanner.Err()
+}
+
+// Judged splits judgements into the ones with relevant documents, which can be measured,
+// and ids of the others, skipped like trec_eval does rather than scoring 0
+func Judged(judgements []Judgement) ([]Judgement, []string) {
+	judged, skipped := make([]Judgement, 0, len(judgements)), make([]string, 0)
+	for _, j := range judgements {
+		relevant := false
+		for _, grade := range j.Relevance {
+			relevant = relevant || grade > 0
+		}
+		if relevant {
+			judged = append(judged, j)
+		} else {
+			skipped = append(skipped, j.ID)
+		}
+	}
+	return judged, skipped
+}
+
+// DocumentID normalises document's path so that judged and retrieved documents can be compared
+func DocumentID(p string) string {
+	return path.Clean(filepath.ToSlash(p))
+}
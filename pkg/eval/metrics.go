@@ -0,0 +1,88 @@
+package eval
+
+import (
+	"math"
+	"sort"
+)
+
+// Metrics holds retrieval quality measures of a ranking cut off at k
+type Metrics struct {
+	Precision float64
+	Recall    float64
+	AP        float64
+	RR        float64
+	NDCG      float64
+}
+
+// Evaluate measures ranked document ids against graded relevance, considering only top k of them
+func Evaluate(ranked []string, relevance map[string]float64, k int) Metrics {
+	m := Metrics{}
+	if len(ranked) > k {
+		ranked = ranked[:k]
+	}
+
+	relevant := 0
+	grades := make([]float64, 0, len(relevance))
+	for _, grade := range relevance {
+		if grade > 0 {
+			relevant++
+			grades = append(grades, grade)
+		}
+	}
+	if relevant == 0 || k < 1 {
+		return m
+	}
+
+	found, dcg := 0, 0.0
+	for i, doc := range ranked {
+		grade := relevance[doc]
+		if grade <= 0 {
+			continue
+		}
+		found++
+		if found == 1 {
+			m.RR = 1.0 / float64(i+1)
+		}
+		m.AP += float64(found) / float64(i+1)
+		dcg += gain(grade, i)
+	}
+
+	sort.Sort(sort.Reverse(sort.Float64Slice(grades)))
+	idcg := 0.0
+	for i := 0; i < len(grades) && i < k; i++ {
+		idcg += gain(grades[i], i)
+	}
+
+	m.Precision = float64(found) / float64(k)
+	m.Recall = float64(found) / float64(relevant)
+	m.AP /= float64(relevant)
+	m.NDCG = dcg / idcg
+
+	return m
+}
+
+// Mean averages metrics over all evaluated queries
+func Mean(metrics []Metrics) Metrics {
+	mean := Metrics{}
+	if len(metrics) == 0 {
+		return mean
+	}
+	for _, m := range metrics {
+		mean.Precision += m.Precision
+		mean.Recall += m.Recall
+		mean.AP += m.AP
+		mean.RR += m.RR
+		mean.NDCG += m.NDCG
+	}
+	n := float64(len(metrics))
+	mean.Precision /= n
+	mean.Recall /= n
+	mean.AP /= n
+	mean.RR /= n
+	mean.NDCG /= n
+	return mean
+}
+
+func gain(grade float64, rank int) float64 {
+	return (math.Pow(2, grade) - 1) / math.Log2(float64(rank+2))
+}
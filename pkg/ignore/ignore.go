@@ -0,0 +1,116 @@
+package ignore
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Rules are patterns of a gitignore style file, matching paths relative to the directory the file is in
+type Rules struct {
+	dir      string
+	patterns []pattern
+}
+
+type pattern struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// Matcher is a list of rules, where later rules, such as those of subdirectories, override earlier ones
+type Matcher []*Rules
+
+// Parse reads patterns, one per line, applying to paths under given directory.
+// Blank lines and lines starting with # are skipped, ! negates the pattern, trailing / makes it match only directories,
+// and patterns without a slash other than a trailing one match names at any depth. * and ? do not match /, ** matches any directories
+func Parse(dir string, r io.Reader) (*Rules, error) {
+	rules := &Rules{dir: dir}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p := pattern{}
+		if strings.HasPrefix(line, "!") {
+			p.negate, line = true, line[1:]
+		} else if strings.HasPrefix(line, "\\") {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		p.segments = strings.Split(strings.TrimPrefix(line, "/"), "/")
+		rules.patterns = append(rules.patterns, p)
+	}
+	return rules, scanner.Err()
+}
+
+// Load reads rules of the named file in given directory, returning nil rules if there is no such file
+func Load(dir string, name string) (*Rules, error) {
+	f, err := os.Open(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(dir, f)
+}
+
+// Match tells whether the path is ignored by the last pattern matching it, and whether any pattern matched it at all
+func (r *Rules) Match(p string, isDir bool) (ignored bool, matched bool) {
+	rel, err := filepath.Rel(r.dir, p)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false, false
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+
+	for i := len(r.patterns) - 1; i >= 0; i-- {
+		p := r.patterns[i]
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if match(p.segments, segments) {
+			return !p.negate, true
+		}
+	}
+	return false, false
+}
+
+// Ignored tells whether the path is ignored by the last of rules matching it
+func (m Matcher) Ignored(p string, isDir bool) bool {
+	for i := len(m) - 1; i >= 0; i-- {
+		if ignored, matched := m[i].Match(p, isDir); matched {
+			return ignored
+		}
+	}
+	return false
+}
+
+// match matches segments of the path against segments of the pattern, where ** matches any number of them
+func match(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		return match(pattern[1:], segments) || (len(segments) > 0 && match(pattern, segments[1:]))
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], segments[0])
+	return ok && err == nil && match(pattern[1:], segments[1:])
+}
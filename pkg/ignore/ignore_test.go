@@ -0,0 +1,90 @@
+package ignore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	rules, err := Parse("root", strings.NewReader(`
+# comments and blank lines are skipped
+*.log
+!keep.log
+node_modules/
+/build
+docs/**/draft*
+\#notes.txt
+`))
+	if err != nil {
+		t.Fatalf("error parsing rules %s", err.Error())
+	}
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"root/debug.log", false, true},
+		{"root/a/b/debug.log", false, true},
+		{"root/a/keep.log", false, false},
+		{"root/node_modules", true, true},
+		{"root/a/node_modules", true, true},
+		{"root/node_modules", false, false},
+		{"root/build", true, true},
+		{"root/a/build", true, false},
+		{"root/docs/draft.txt", false, true},
+		{"root/docs/a/b/draft1.txt", false, true},
+		{"root/docs/final.txt", false, false},
+		{"root/#notes.txt", false, true},
+		{"other/debug.log", false, false},
+		{"root", true, false},
+	}
+	for _, test := range tests {
+		if ignored, _ := rules.Match(filepath.FromSlash(test.path), test.isDir); ignored != test.ignored {
+			t.Errorf("expected %q ignored=%v, got: %v", test.path, test.ignored, ignored)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ".qdoxignore"), []byte("*.txt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(sub, ".qdoxignore"), []byte("!*.txt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := Matcher{}
+	for _, d := range []string{dir, sub, filepath.Join(dir, "missing")} {
+		rules, err := Load(d, ".qdoxignore")
+		if err != nil {
+			t.Fatalf("error loading rules %s", err.Error())
+		}
+		if rules != nil {
+			m = append(m, rules)
+		}
+	}
+	if len(m) != 2 {
+		t.Fatalf("expected rules of 2 directories, got: %d", len(m))
+	}
+
+	if !m.Ignored(filepath.Join(dir, "a.txt"), false) {
+		t.Errorf("expected text file of the root to be ignored")
+	}
+	if m.Ignored(filepath.Join(sub, "a.txt"), false) {
+		t.Errorf("expected rules of the subdirectory to override the root ones")
+	}
+}
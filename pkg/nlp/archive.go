@@ -0,0 +1,231 @@
+package nlp
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveSeparator separates path of an archive from path of its member in virtual paths of documents, e.g. bundle.zip!/dir/file.txt
+const ArchiveSeparator = "!/"
+
+// IsArchive tells whether the file is a zip or tar archive, optionally gzipped, by its extension
+func IsArchive(p string) bool {
+	p = strings.ToLower(p)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(p, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitArchivePath splits virtual path of an archive member into path of the archive and path of the member within it
+func SplitArchivePath(p string) (archive string, member string, ok bool) {
+	i := strings.Index(p, ArchiveSeparator)
+	if i < 0 || !IsArchive(p[:i]) {
+		return p, "", false
+	}
+	return p[:i], p[i+len(ArchiveSeparator):], true
+}
+
+// ReadArchived returns content and modification time of the archive member at given virtual path.
+// Zip archives are kept open to read their members directly, while tar archives are read up to the member
+func ReadArchived(p string) ([]byte, time.Time, error) {
+	archive, member, ok := SplitArchivePath(p)
+	if !ok {
+		return nil, time.Time{}, fmt.Errorf("%q is not a path of an archive member", p)
+	}
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		return openZips.read(archive, member)
+	}
+
+	var content []byte
+	var modified time.Time
+	found := false
+	err := walkArchive(archive, func(name string, info os.FileInfo, r io.Reader) error {
+		if found || name != member {
+			return nil
+		}
+		found, modified = true, info.ModTime()
+		var err error
+		content, err = ioutil.ReadAll(r)
+		return err
+	})
+	if err == nil && !found {
+		err = os.ErrNotExist
+	}
+	return content, modified, err
+}
+
+// maxOpenZips is the number of zip archives kept open to read their members, closing the least recently read one beyond it
+const maxOpenZips = 16
+
+// openZips keeps zip archives read from open, along with their members by name
+var openZips = &zipArchives{archives: make(map[string]*openZip)}
+
+// zipArchives are zip archives kept open, until they change or others are read
+type zipArchives struct {
+	sync.Mutex
+	archives map[string]*openZip
+	// recent paths of archives, least recently read first
+	recent []string
+}
+
+type openZip struct {
+	*zip.ReadCloser
+	members  map[string]*zip.File
+	size     int64
+	modified time.Time
+}
+
+// read returns content and modification time of the member, opening the archive unless it is open and unchanged since
+func (z *zipArchives) read(archive, member string) ([]byte, time.Time, error) {
+	info, err := os.Stat(archive)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	z.Lock()
+	defer z.Unlock()
+	open, ok := z.archives[archive]
+	if ok && (open.size != info.Size() || !open.modified.Equal(info.ModTime())) {
+		z.close(archive)
+		ok = false
+	}
+	if !ok {
+		if open, err = openZipArchive(archive, info); err != nil {
+			return nil, time.Time{}, err
+		}
+		z.archives[archive] = open
+		if len(z.recent) >= maxOpenZips {
+			z.close(z.recent[0])
+		}
+	}
+	z.touch(archive)
+
+	f, ok := open.members[member]
+	if !ok {
+		return nil, time.Time{}, os.ErrNotExist
+	}
+	r, err := f.Open()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer r.Close()
+	content, err := ioutil.ReadAll(r)
+	return content, f.FileInfo().ModTime(), err
+}
+
+// touch marks the archive as the most recently read one
+func (z *zipArchives) touch(archive string) {
+	for i, p := range z.recent {
+		if p == archive {
+			z.recent = append(z.recent[:i], z.recent[i+1:]...)
+			break
+		}
+	}
+	z.recent = append(z.recent, archive)
+}
+
+// close closes the archive and forgets it
+func (z *zipArchives) close(archive string) {
+	if open, ok := z.archives[archive]; ok {
+		open.Close()
+		delete(z.archives, archive)
+	}
+	for i, p := range z.recent {
+		if p == archive {
+			z.recent = append(z.recent[:i], z.recent[i+1:]...)
+			break
+		}
+	}
+}
+
+// openZipArchive opens the archive of given size and modification time, indexing its regular files by cleaned names
+func openZipArchive(archive string, info os.FileInfo) (*openZip, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	open := &openZip{ReadCloser: r, members: make(map[string]*zip.File, len(r.File)), size: info.Size(), modified: info.ModTime()}
+	for _, f := range r.File {
+		if name := cleanMember(f.Name); !f.FileInfo().IsDir() && open.members[name] == nil {
+			open.members[name] = f
+		}
+	}
+	return open, nil
+}
+
+// walkArchive calls fn with cleaned name, info and content of every regular file of the archive, in order of the archive
+func walkArchive(archive string, fn func(name string, info os.FileInfo, r io.Reader) error) error {
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		z, err := zip.OpenReader(archive)
+		if err != nil {
+			return err
+		}
+		defer z.Close()
+
+		for _, f := range z.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = fn(cleanMember(f.Name), f.FileInfo(), r)
+			r.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if !strings.HasSuffix(strings.ToLower(archive), ".tar") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	t := tar.NewReader(r)
+	for {
+		header, err := t.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(cleanMember(header.Name), header.FileInfo(), t); err != nil {
+			return err
+		}
+	}
+}
+
+// cleanMember returns slash separated name of the member relative to the root of the archive, which cannot escape it
+func cleanMember(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.Replace(name, "\\", "/", -1)), "/")
+}
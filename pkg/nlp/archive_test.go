@@ -0,0 +1,164 @@
+package nlp
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestLoadArchives(t *testing.T) {
+	dir := writeFiles(t, nil)
+	defer os.RemoveAll(dir)
+
+	members := []struct{ name, content string }{
+		{"docs/one.txt", "geysers of yellowstone"},
+		{"two.txt", "grizzly bears"},
+		{".cache/three.txt", "hidden"},
+		{".env.txt", "hidden file"},
+		{"image.png", "not matching"},
+	}
+
+	f, err := os.Create(filepath.Join(dir, "bundle.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	for _, m := range members {
+		w, err := z.Create(m.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(m.content))
+	}
+	z.Close()
+	f.Close()
+
+	f, err = os.Create(filepath.Join(dir, "bundle.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, m := range members {
+		tw.WriteHeader(&tar.Header{Name: "./" + m.name, Mode: 0644, Size: int64(len(m.content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(m.content))
+	}
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "broken.zip"), []byte("not a zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second member of the truncated archive fails to read after the first one is read
+	f, err = os.Create(filepath.Join(dir, "truncated.tar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw = tar.NewWriter(f)
+	tw.WriteHeader(&tar.Header{Name: "first.txt", Mode: 0644, Size: 5, Typeflag: tar.TypeReg})
+	tw.Write([]byte("first"))
+	tw.WriteHeader(&tar.Header{Name: "second.txt", Mode: 0644, Size: 100, Typeflag: tar.TypeReg})
+	tw.Write([]byte("second"))
+	f.Close()
+
+	c := NewCorpus()
+	pattern := regexp.MustCompile("\\.txt$")
+	if err := c.LoadWith(dir, Filter{Pattern: pattern}); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	if c.Len() != 0 {
+		t.Errorf("expected archives to be skipped, got: %v", c.Paths())
+	}
+
+	if err := c.LoadWith(dir, Filter{Pattern: pattern, Archives: true}); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	want := []string{
+		filepath.Join(dir, "bundle.tar.gz") + "!/docs/one.txt",
+		filepath.Join(dir, "bundle.tar.gz") + "!/two.txt",
+		filepath.Join(dir, "bundle.tar.gz") + "!/.env.txt",
+		filepath.Join(dir, "bundle.zip") + "!/docs/one.txt",
+		filepath.Join(dir, "bundle.zip") + "!/two.txt",
+		filepath.Join(dir, "bundle.zip") + "!/.env.txt",
+	}
+	if got := c.Paths(); !reflect.DeepEqual(want, got) {
+		t.Errorf("expected members %v, got: %v", want, got)
+	}
+	wantSkipped := []Skipped{
+		{filepath.Join(dir, "broken.zip"), SkipUndecodable},
+		{filepath.Join(dir, "bundle.tar.gz") + "!/.cache/three.txt", SkipHidden},
+		{filepath.Join(dir, "bundle.zip") + "!/.cache/three.txt", SkipHidden},
+		{filepath.Join(dir, "truncated.tar"), SkipUndecodable},
+	}
+	if got := c.Skipped(); !reflect.DeepEqual(wantSkipped, got) {
+		t.Errorf("expected to skip %v, got: %v", wantSkipped, got)
+	}
+
+	c.Release()
+	for i, content := range []string{"geysers of yellowstone", "grizzly bears", "hidden file", "geysers of yellowstone", "grizzly bears", "hidden file"} {
+		if text, err := c.Read(i); err != nil || text != content {
+			t.Errorf("expected to read %q from %q, got: %q %v", content, c.GetPath(i), text, err)
+		}
+	}
+
+	if archive, member, ok := SplitArchivePath(want[0]); !ok || archive != filepath.Join(dir, "bundle.tar.gz") || member != "docs/one.txt" {
+		t.Errorf("expected to split %q, got: %q %q %v", want[0], archive, member, ok)
+	}
+	if _, _, err := ReadArchived(filepath.Join(dir, "bundle.zip") + "!/missing.txt"); !os.IsNotExist(err) {
+		t.Errorf("expected missing member not to exist, got: %v", err)
+	}
+}
+
+func TestReadArchivedZip(t *testing.T) {
+	dir := writeFiles(t, nil)
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "bundle.zip")
+	write := func(content string, modified time.Time) {
+		f, err := os.Create(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		z := zip.NewWriter(f)
+		w, err := z.Create("docs/one.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+		z.Close()
+		f.Close()
+		if err := os.Chtimes(archive, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the archive is kept open for later reads, until it changes
+	write("geysers", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	for i := 0; i < 2; i++ {
+		if content, _, err := ReadArchived(archive + "!/docs/one.txt"); err != nil || string(content) != "geysers" {
+			t.Errorf("expected to read geysers, got: %q %v", content, err)
+		}
+	}
+	if open := openZips.archives[archive]; open == nil {
+		t.Errorf("expected the archive to be kept open")
+	}
+	write("grizzly bears", time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+	if content, _, err := ReadArchived(archive + "!/docs/one.txt"); err != nil || string(content) != "grizzly bears" {
+		t.Errorf("expected to read changed archive, got: %q %v", content, err)
+	}
+	if _, _, err := ReadArchived(archive + "!/docs"); !os.IsNotExist(err) {
+		t.Errorf("expected folders not to be read, got: %v", err)
+	}
+	openZips.Lock()
+	openZips.close(archive)
+	openZips.Unlock()
+}
@@ -0,0 +1,138 @@
+package nlp
+
+import (
+	"fmt"
+	"sort"
+)
+
+const maxClusterIterations = 100
+
+// Cluster is a group of thematically similar documents, labelled with its top terms
+type Cluster struct {
+	Terms        []string
+	Members      []int
+	Similarities []float64
+}
+
+// Cluster groups trained documents into at most k clusters using spherical k-means over LSI vectors
+func (m *Model) Cluster(k int, terms int) ([]Cluster, error) {
+	if m.Matrix == nil {
+		return nil, fmt.Errorf("model is not trained")
+	}
+	if k < 1 {
+		return nil, fmt.Errorf("number of clusters should be a positive integer")
+	}
+
+	vectors := m.documentVectors()
+	if len(vectors) == 0 {
+		return []Cluster{}, nil
+	}
+	if k > len(vectors) {
+		k = len(vectors)
+	}
+
+	centroids := seedCentroids(vectors, k)
+	assignment := make([]int, len(vectors))
+	for i := range assignment {
+		assignment[i] = -1
+	}
+
+	for iter := 0; iter < maxClusterIterations; iter++ {
+		changed := false
+		for i, v := range vectors {
+			best := nearestCentroid(v, centroids)
+			if best != assignment[i] {
+				assignment[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		centroids = updateCentroids(vectors, assignment, centroids)
+	}
+
+	clusters := make([]Cluster, 0, k)
+	for c, centroid := range centroids {
+		cluster := Cluster{m.topTerms(centroid, terms), make([]int, 0), make([]float64, 0)}
+		for i, a := range assignment {
+			if a == c {
+				cluster.Members = append(cluster.Members, i)
+				cluster.Similarities = append(cluster.Similarities, dot(vectors[i], centroid))
+			}
+		}
+		if len(cluster.Members) == 0 {
+			continue
+		}
+		sort.Sort(&cluster)
+		clusters = append(clusters, cluster)
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool { return len(clusters[j].Members) < len(clusters[i].Members) })
+
+	return clusters, nil
+}
+
+// seedCentroids deterministically picks k documents spread furthest apart from each other
+func seedCentroids(vectors [][]float64, k int) [][]float64 {
+	centroids := [][]float64{append([]float64(nil), vectors[0]...)}
+	for len(centroids) < k {
+		far, farSim := 0, 2.0
+		for i, v := range vectors {
+			sim := dot(v, centroids[nearestCentroid(v, centroids)])
+			if sim < farSim {
+				far, farSim = i, sim
+			}
+		}
+		centroids = append(centroids, append([]float64(nil), vectors[far]...))
+	}
+	return centroids
+}
+
+func nearestCentroid(v []float64, centroids [][]float64) int {
+	best, bestSim := 0, -2.0
+	for c, centroid := range centroids {
+		if sim := dot(v, centroid); sim > bestSim {
+			best, bestSim = c, sim
+		}
+	}
+	return best
+}
+
+// updateCentroids averages members of each cluster, keeping previous centroid for empty ones
+func updateCentroids(vectors [][]float64, assignment []int, previous [][]float64) [][]float64 {
+	centroids := make([][]float64, len(previous))
+	for c := range centroids {
+		centroids[c] = make([]float64, len(previous[c]))
+	}
+	counts := make([]int, len(previous))
+	for i, c := range assignment {
+		counts[c]++
+		for j, x := range vectors[i] {
+			centroids[c][j] += x
+		}
+	}
+	for c := range centroids {
+		if counts[c] == 0 {
+			copy(centroids[c], previous[c])
+			continue
+		}
+		normalise(centroids[c])
+	}
+	return centroids
+}
+
+// Ordering of cluster members
+
+func (c *Cluster) Len() int {
+	return len(c.Members)
+}
+
+func (c *Cluster) Swap(i, j int) {
+	c.Members[i], c.Members[j] = c.Members[j], c.Members[i]
+	c.Similarities[i], c.Similarities[j] = c.Similarities[j], c.Similarities[i]
+}
+
+func (c *Cluster) Less(i, j int) bool {
+	return c.Similarities[j] < c.Similarities[i]
+}
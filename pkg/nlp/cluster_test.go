@@ -0,0 +1,50 @@
+package nlp
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestCluster(t *testing.T) {
+	c := NewCorpus()
+	if err := c.Load("../../books", regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	m := NewLSIModel()
+	if err := m.Train(&c); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+
+	clusters, err := m.Cluster(2, 3)
+	if err != nil {
+		t.Fatalf("error clustering documents %s", err.Error())
+	}
+
+	if len(clusters) != 2 {
+		t.Errorf("expected 2 clusters, got: %d", len(clusters))
+	}
+
+	seen := make(map[int]bool)
+	for _, cluster := range clusters {
+		if len(cluster.Terms) != 3 {
+			t.Errorf("expected 3 terms per cluster, got: %v", cluster.Terms)
+		}
+		for _, doc := range cluster.Members {
+			if seen[doc] {
+				t.Errorf("document %d assigned to more than one cluster", doc)
+			}
+			seen[doc] = true
+		}
+	}
+
+	if len(seen) != 4 {
+		t.Errorf("expected all 4 documents to be clustered, got: %d", len(seen))
+	}
+}
+
+func TestClusterUntrained(t *testing.T) {
+	m := NewLSIModel()
+	if _, err := m.Cluster(2, 3); err == nil {
+		t.Errorf("expected error for untrained model")
+	}
+}
@@ -0,0 +1,29 @@
+package nlp
+
+import (
+	"github.com/stormcrows/qdox/pkg/suggest"
+)
+
+// Complete returns up to n vocabulary terms starting with prefix, the ones appearing in most documents first
+func (m *Model) Complete(prefix string, n int) []string {
+	if m.completions == nil {
+		return []string{}
+	}
+	completions := m.completions.Complete(prefix, n)
+	terms := make([]string, len(completions))
+	for i, c := range completions {
+		terms[i] = c.Text
+	}
+	return terms
+}
+
+// buildCompletions fills prefix tree with vocabulary terms weighted by their document frequency
+func (m *Model) buildCompletions() {
+	completions := suggest.NewTrie()
+	for i, term := range m.Vocabulary() {
+		if i < len(m.frequencies) {
+			completions.Add(term, m.frequencies[i])
+		}
+	}
+	m.completions = completions
+}
@@ -1,26 +1,53 @@
 package nlp
 
 import (
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stormcrows/qdox/pkg/ignore"
+	"github.com/stormcrows/qdox/pkg/store"
 )
 
-// Document holds content of the file and its path
+// Document holds content of the file and its path, along with content's hash, fingerprint and metadata
 type Document struct {
-	content string
-	path    string
+	content     string
+	path        string
+	hash        string
+	fingerprint uint64
+	metadata    map[string]string
+	// read reads content of documents which are not files, such as past revisions, once released
+	read func() ([]byte, error)
+	// since is when the content was first seen, kept in the store if there is one
+	since time.Time
 }
 
 // Corpus is a list of documents
 type Corpus struct {
 	documents []Document
+	skipped   []Skipped
+	filter    Filter
+	store     *store.Store
+	reused    int
+	// shard names the shard the corpus is part of, if any
+	shard string
 }
 
 // NewCorpus returns an empty corpus
 func NewCorpus() Corpus {
-	return Corpus{make([]Document, 0)}
+	return Corpus{documents: make([]Document, 0)}
+}
+
+// Copy returns a corpus with its own list of the same documents, so that releasing contents of either keeps the other's
+func (c *Corpus) Copy() Corpus {
+	copied := *c
+	copied.documents = append([]Document(nil), c.documents...)
+	return copied
 }
 
 // Release contents from memory after training
@@ -32,37 +59,156 @@ func (c *Corpus) Release() {
 
 // Load walks given path recursively and adds documents to the corpus, that match the pattern
 func (c *Corpus) Load(path string, pattern *regexp.Regexp) error {
-	n, err := CountDocuments(path, pattern)
+	return c.LoadWith(path, Filter{Pattern: pattern})
+}
+
+// LoadWith walks given path recursively and replaces documents of the corpus with files passing the filter,
+// noting skipped ones
+func (c *Corpus) LoadWith(root string, filter Filter) error {
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	excluded, err := ignore.Parse(root, strings.NewReader(strings.Join(filter.Exclude, "\n")))
 	if err != nil {
 		return err
 	}
+	stored, err := c.openStored(filter)
+	if err != nil {
+		return err
+	}
+	ignored := ignore.Matcher{}
+	loaded := &loading{documents: make([]Document, 0), skipped: make([]Skipped, 0), stored: stored}
+
+	// add decodes content of the file into a document of the load, unless it is too large, binary or fails to decode,
+	// or reuses the stored one if the file has not changed
+	add := func(l *loading, path string, info os.FileInfo, r io.Reader) error {
+		if filter.MaxSize > 0 && info.Size() > filter.MaxSize {
+			l.skipped = append(l.skipped, Skipped{path, SkipTooLarge})
+			return nil
+		}
+		if doc, ok := l.stored.get(path, info.Size(), info.ModTime()); ok {
+			l.documents = append(l.documents, doc)
+			return nil
+		}
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		doc, reason := decodeDocument(path, content, filter)
+		if reason != "" {
+			l.skipped = append(l.skipped, Skipped{path, reason})
+			return nil
+		}
+		for key, value := range fileMetadata(info) {
+			doc.metadata[key] = value
+		}
+		l.stored.put(&doc, info.Size(), info.ModTime())
+		l.documents = append(l.documents, doc)
+		return nil
+	}
 
-	c.documents = make([]Document, n)
-	i := 0
+	// addMembers adds members of the archive passing the filter as documents with virtual paths,
+	// collecting them apart so that none are added if the archive fails to read
+	addMembers := func(archive string) error {
+		members := &loading{stored: stored.nested()}
+		err := walkArchive(archive, func(name string, info os.FileInfo, r io.Reader) error {
+			path := archive + ArchiveSeparator + name
+			if !filter.matches(path) {
+				return nil
+			}
+			if skip, _ := excluded.Match(path, false); skip {
+				members.skipped = append(members.skipped, Skipped{path, SkipExcluded})
+			} else if ignored.Ignored(path, false) {
+				members.skipped = append(members.skipped, Skipped{path, SkipIgnored})
+			} else if !filter.Hidden && inHiddenDirectory(name) {
+				members.skipped = append(members.skipped, Skipped{path, SkipHidden})
+			} else {
+				return add(members, path, info, r)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		loaded.documents = append(loaded.documents, members.documents...)
+		loaded.skipped = append(loaded.skipped, members.skipped...)
+		stored.merge(members.stored)
+		return nil
+	}
 
-	return filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		archive := !info.IsDir() && filter.Archives && IsArchive(path)
+		if !info.IsDir() && !archive && !filter.matches(path) {
+			return nil
+		}
+
+		reason := ""
+		if path != root {
+			if skip, _ := excluded.Match(path, info.IsDir()); skip {
+				reason = SkipExcluded
+			} else if ignored.Ignored(path, info.IsDir()) {
+				reason = SkipIgnored
+			} else if info.IsDir() && !filter.Hidden && strings.HasPrefix(info.Name(), ".") {
+				reason = SkipHidden
+			}
+		}
+		if reason != "" {
+			loaded.skipped = append(loaded.skipped, Skipped{path, reason})
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 
 		if info.IsDir() {
+			rules, err := ignore.Load(path, IgnoreFile)
+			if err != nil {
+				return err
+			}
+			if rules != nil {
+				ignored = append(ignored, rules)
+			}
 			return nil
 		}
 
-		if ok := pattern.MatchString(path); !ok {
+		if archive {
+			if err := addMembers(path); err != nil {
+				loaded.skipped = append(loaded.skipped, Skipped{path, SkipUndecodable})
+			}
 			return nil
 		}
 
-		content, err := ioutil.ReadFile(path)
+		f, err := os.Open(path)
 		if err != nil {
 			return err
 		}
+		defer f.Close()
+		return add(loaded, path, info, f)
+	})
+	if err != nil {
+		return err
+	}
+	if err := stored.save(loaded.documents, filter); err != nil {
+		return err
+	}
 
-		c.documents[i] = Document{string(content), path}
-		i++
+	c.documents, c.skipped, c.filter, c.reused = loaded.documents, loaded.skipped, filter, stored.reused
+	return nil
+}
 
-		return nil
-	})
+// loading collects documents and skipped files of a load, or of an archive until all of its members are read
+type loading struct {
+	documents []Document
+	skipped   []Skipped
+	stored    *storedDocuments
+}
+
+// Skipped returns files and directories left out of the corpus by the last load, along with reasons
+func (c *Corpus) Skipped() []Skipped {
+	return c.skipped
 }
 
 // CountDocuments returns number of documents under given path for given pattern
@@ -103,7 +249,71 @@ func (c *Corpus) Paths() []string {
 	return paths
 }
 
+// Len returns number of loaded documents
+func (c *Corpus) Len() int {
+	return len(c.documents)
+}
+
 // GetPath returns path for given document's index
 func (c *Corpus) GetPath(i int) string {
 	return c.documents[i].path
 }
+
+// GetHash returns hash of the content for given document's index
+func (c *Corpus) GetHash(i int) string {
+	return c.documents[i].hash
+}
+
+// GetMetadata returns metadata of the document for given document's index
+func (c *Corpus) GetMetadata(i int) map[string]string {
+	return c.documents[i].metadata
+}
+
+// Read returns content of the document for given document's index once released, from the store if the corpus has one,
+// or reading and decoding it again
+func (c *Corpus) Read(i int) (string, error) {
+	if c.documents[i].content != "" {
+		return c.documents[i].content, nil
+	}
+	if c.store != nil {
+		if r, ok, err := c.store.Get(c.documents[i].path); err == nil && ok {
+			return r.Content, nil
+		}
+	}
+	var content []byte
+	var err error
+	if c.documents[i].read != nil {
+		content, err = c.documents[i].read()
+	} else if _, _, ok := SplitArchivePath(c.documents[i].path); ok {
+		content, _, err = ReadArchived(c.documents[i].path)
+	} else {
+		content, err = ioutil.ReadFile(c.documents[i].path)
+	}
+	if err != nil {
+		return "", err
+	}
+	text, _, err := decode(content, c.filter)
+	return text, err
+}
+
+// decodeDocument decodes content of the file into a document, or returns the reason to skip it
+func decodeDocument(path string, content []byte, filter Filter) (Document, string) {
+	text, encoding, err := decode(content, filter)
+	if err == errBinary {
+		return Document{}, SkipBinary
+	} else if err != nil {
+		return Document{}, SkipUndecodable
+	}
+	doc := newDocument(text, path)
+	if encoding != "utf-8" {
+		doc.metadata["encoding"] = encoding
+	}
+	return doc, ""
+}
+
+func fileMetadata(info os.FileInfo) map[string]string {
+	return map[string]string{
+		"size":     strconv.FormatInt(info.Size(), 10),
+		"modified": info.ModTime().UTC().Format(time.RFC3339),
+	}
+}
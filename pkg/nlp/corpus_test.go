@@ -1,6 +1,9 @@
 package nlp
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
 	"testing"
 )
@@ -47,6 +50,48 @@ func TestRelease(t *testing.T) {
 	}
 }
 
+func TestCopy(t *testing.T) {
+	c := NewCorpus()
+	if err := c.Load("../../books", regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+
+	copied := c.Copy()
+	m := NewLSIModel()
+	terms := m.Terms(&copied)
+	if terms == 0 || m.Matrix != nil {
+		t.Errorf("expected terms of the corpus without training, got: %d", terms)
+	}
+	if err := m.Train(&copied); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+	if len(m.Vocabulary()) != terms {
+		t.Errorf("expected %d terms to be trained, got: %d", terms, len(m.Vocabulary()))
+	}
+	for _, doc := range c.documents {
+		if doc.content == "" {
+			t.Errorf("expecting content of %q to outlive training of the copy", doc.path)
+		}
+	}
+}
+
+func TestInHiddenDirectory(t *testing.T) {
+	for name, hidden := range map[string]bool{
+		"a.txt":          false,
+		".env.txt":       false,
+		"docs/.env.txt":  false,
+		"./docs/a.txt":   false,
+		".git/HEAD.txt":  true,
+		"docs/.cache/a":  true,
+		"../docs/a.txt":  false,
+		"docs/..a/b.txt": true,
+	} {
+		if got := inHiddenDirectory(name); got != hidden {
+			t.Errorf("expected %q to be in hidden directory %v, got: %v", name, hidden, got)
+		}
+	}
+}
+
 func TestCountDocuments(t *testing.T) {
 	r := regexp.MustCompile("\\.txt")
 	n, err := CountDocuments("../../books", r)
@@ -58,3 +103,56 @@ func TestCountDocuments(t *testing.T) {
 		t.Errorf("expected 4 documents, but got: %d", n)
 	}
 }
+
+func TestLoadWith(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.txt":                  "kept",
+		".env.txt":               "kept",
+		"big.txt":                "far too large to be loaded",
+		"binary.txt":             "\x00\x01\x02",
+		"notes.md":               "not matching the pattern",
+		"logs/debug.txt":         "ignored by the root",
+		"docs/b.txt":             "kept",
+		"docs/draft.txt":         "ignored by the subfolder",
+		".git/HEAD.txt":          "hidden",
+		"node_modules/lib/c.txt": "excluded",
+		IgnoreFile:               "logs/\n",
+		"docs/" + IgnoreFile:     "draft*\n",
+		"vendor/" + IgnoreFile:   "*\n!keep.txt\n",
+		"vendor/keep.txt":        "kept",
+		"vendor/skip.txt":        "ignored by the subfolder",
+	})
+	defer os.RemoveAll(dir)
+
+	c := NewCorpus()
+	filter := Filter{Pattern: regexp.MustCompile("\\.txt$"), Exclude: []string{"node_modules/"}, MaxSize: 10}
+	if err := c.LoadWith(dir, filter); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+
+	want := []string{filepath.Join(dir, ".env.txt"), filepath.Join(dir, "a.txt"), filepath.Join(dir, "docs/b.txt"), filepath.Join(dir, "vendor/keep.txt")}
+	if got := c.Paths(); !reflect.DeepEqual(want, got) {
+		t.Errorf("expected to load %v, got: %v", want, got)
+	}
+
+	wantSkipped := []Skipped{
+		{filepath.Join(dir, ".git"), SkipHidden},
+		{filepath.Join(dir, "big.txt"), SkipTooLarge},
+		{filepath.Join(dir, "binary.txt"), SkipBinary},
+		{filepath.Join(dir, "docs/draft.txt"), SkipIgnored},
+		{filepath.Join(dir, "logs"), SkipIgnored},
+		{filepath.Join(dir, "node_modules"), SkipExcluded},
+		{filepath.Join(dir, "vendor/skip.txt"), SkipIgnored},
+	}
+	if got := c.Skipped(); !reflect.DeepEqual(wantSkipped, got) {
+		t.Errorf("expected to skip %v, got: %v", wantSkipped, got)
+	}
+
+	filter.Hidden = true
+	if err := c.LoadWith(dir, filter); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	if c.Len() != 5 {
+		t.Errorf("expected hidden folder to be loaded, got: %v", c.Paths())
+	}
+}
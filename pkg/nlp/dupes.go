@@ -0,0 +1,155 @@
+package nlp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"math/bits"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const shingleSize = 3
+
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// DuplicateGroup lists documents whose contents are identical or nearly identical to the Original
+type DuplicateGroup struct {
+	Original     int
+	Copies       []int
+	Similarities []float64
+}
+
+func newDocument(content string, path string) Document {
+	sum := sha256.Sum256([]byte(content))
+	return Document{content: content, path: path, hash: hex.EncodeToString(sum[:]), fingerprint: simhash(content), metadata: map[string]string{}}
+}
+
+// Resemblance estimates similarity of two documents' contents comparing their fingerprints
+func (c *Corpus) Resemblance(i, j int) float64 {
+	if c.documents[i].hash == c.documents[j].hash {
+		return 1.0
+	}
+	distance := bits.OnesCount64(c.documents[i].fingerprint ^ c.documents[j].fingerprint)
+	return 1.0 - float64(distance)/64.0
+}
+
+// Duplicates groups documents with the same content hash, or fingerprints resembling at least given similarity.
+// Exact copies are grouped by hash, and fingerprints are compared only to the ones sharing a band of bits with them
+func (c *Corpus) Duplicates(similarity float64) []DuplicateGroup {
+	// every copy follows the first document with its hash
+	first := make(map[string]int)
+	copies := make(map[int][]int)
+	unique := make([]int, 0, len(c.documents))
+	for i, doc := range c.documents {
+		if j, ok := first[doc.hash]; ok {
+			copies[j] = append(copies[j], i)
+			continue
+		}
+		first[doc.hash] = i
+		unique = append(unique, i)
+	}
+
+	masks := bands(maxDistance(similarity))
+	buckets := make([]map[uint64][]int, len(masks))
+	for b, mask := range masks {
+		buckets[b] = make(map[uint64][]int)
+		for _, i := range unique {
+			key := c.documents[i].fingerprint & mask
+			buckets[b][key] = append(buckets[b][key], i)
+		}
+	}
+
+	groups := make([]DuplicateGroup, 0)
+	grouped := make([]bool, len(c.documents))
+	for _, i := range unique {
+		if grouped[i] {
+			continue
+		}
+
+		candidates := make(map[int]bool)
+		for b, mask := range masks {
+			for _, j := range buckets[b][c.documents[i].fingerprint&mask] {
+				if j > i && !grouped[j] {
+					candidates[j] = true
+				}
+			}
+		}
+
+		members := append([]int{}, copies[i]...)
+		for j := range candidates {
+			if c.Resemblance(i, j) >= similarity {
+				members = append(members, j)
+				members = append(members, copies[j]...)
+			}
+		}
+		if len(members) == 0 {
+			continue
+		}
+
+		sort.Ints(members)
+		group := DuplicateGroup{i, members, make([]float64, len(members))}
+		for k, j := range members {
+			group.Similarities[k] = c.Resemblance(i, j)
+			grouped[j] = true
+		}
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// maxDistance returns the most bits fingerprints resembling at least given similarity may differ in
+func maxDistance(similarity float64) int {
+	distance := 0
+	for distance < 64 && 1.0-float64(distance+1)/64.0 >= similarity {
+		distance++
+	}
+	return distance
+}
+
+// bands splits bits of fingerprints into masks, so fingerprints differing in at most distance bits
+// are equal under at least one of them
+func bands(distance int) []uint64 {
+	if distance >= 64 {
+		return []uint64{0}
+	}
+	masks := make([]uint64, distance+1)
+	for b := 0; b < 64; b++ {
+		masks[b*len(masks)/64] |= 1 << uint(b)
+	}
+	return masks
+}
+
+// simhash computes 64 bit fingerprint of the content from its word shingles
+func simhash(content string) uint64 {
+	words := wordPattern.FindAllString(strings.ToLower(content), -1)
+	size := shingleSize
+	if len(words) < size {
+		size = 1
+	}
+
+	var weights [64]int
+	h := fnv.New64a()
+	for i := 0; i+size <= len(words); i++ {
+		h.Reset()
+		h.Write([]byte(strings.Join(words[i:i+size], " ")))
+		sum := h.Sum64()
+		for b := uint(0); b < 64; b++ {
+			if sum&(1<<b) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for b := uint(0); b < 64; b++ {
+		if weights[b] > 0 {
+			fingerprint |= 1 << b
+		}
+	}
+	return fingerprint
+}
@@ -0,0 +1,182 @@
+package nlp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes the files by their slash separated names into a new temporary folder, returning its path
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "qdox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFilesTo(t, dir, files)
+	return dir
+}
+
+// writeFilesTo writes the files by their slash separated names into the folder, creating their subfolders
+func writeFilesTo(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func duplicatesFolder(t *testing.T) string {
+	park, err := ioutil.ReadFile("../../books/Grand Teton National Park.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sausages, err := ioutil.ReadFile("../../books/Butchers Packers and Sausage Makers Red Book.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.Replace(string(park), "Jackson Hole", "Jackson valley", 5)
+
+	return writeFiles(t, map[string]string{
+		"a park.txt":     string(park),
+		"b copy.txt":     string(park),
+		"c edited.txt":   edited,
+		"d sausages.txt": string(sausages),
+	})
+}
+
+func TestDuplicates(t *testing.T) {
+	dir := duplicatesFolder(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCorpus()
+	if err := c.Load(dir, regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+
+	groups := c.Duplicates(0.9)
+	if len(groups) != 1 {
+		t.Fatalf("expected 1 group of duplicates, got: %d", len(groups))
+	}
+
+	group := groups[0]
+	if filepath.Base(c.GetPath(group.Original)) != "a park.txt" {
+		t.Errorf("expected a park.txt to be the original, got: %q", c.GetPath(group.Original))
+	}
+	if len(group.Copies) != 2 {
+		t.Fatalf("expected 2 copies, got: %d", len(group.Copies))
+	}
+	if group.Similarities[0] != 1.0 {
+		t.Errorf("expected exact copy to have similarity 1, got: %f", group.Similarities[0])
+	}
+	if group.Similarities[1] < 0.9 || group.Similarities[1] == 1.0 {
+		t.Errorf("expected edited copy to be a near duplicate, got: %f", group.Similarities[1])
+	}
+
+	if groups := c.Duplicates(1.0); len(groups) != 1 || len(groups[0].Copies) != 1 {
+		t.Errorf("expected only the exact copy at similarity 1, got: %v", groups)
+	}
+}
+
+func TestQueryCollapse(t *testing.T) {
+	dir := duplicatesFolder(t)
+	defer os.RemoveAll(dir)
+
+	c := NewCorpus()
+	if err := c.Load(dir, regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	m := NewLSIModel()
+	if err := m.Train(&c); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+
+	if qr := m.Query("national park", 5, 0.3); len(qr.Matched) != 3 || qr.Versions != nil {
+		t.Errorf("expected 3 separate matches without collapsing, got: %v", qr.Matched)
+	}
+
+	m.Collapse = 0.9
+	qr := m.Query("national park", 5, 0.3)
+	if len(qr.Matched) != 1 {
+		t.Fatalf("expected duplicates to collapse into 1 match, got: %v", qr.Matched)
+	}
+	if len(qr.Versions[0]) != 2 {
+		t.Errorf("expected 2 versions of the match, got: %v", qr.Versions[0])
+	}
+}
+
+func TestShardedCollapse(t *testing.T) {
+	park, err := ioutil.ReadFile("../../books/Grand Teton National Park.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder := writeFiles(t, map[string]string{
+		"a/park.txt":      string(park),
+		"a/sausage.txt":   "sausage pork meat spices casing smoking grill",
+		"a/cheese.txt":    "cheese milk cultures aging cellar rind wheels",
+		"b/park copy.txt": string(park),
+		"b/bread.txt":     "bread flour yeast dough oven baking crust",
+		"b/pasta.txt":     "pasta flour eggs dough boiling sauce tomatoes",
+	})
+	defer os.RemoveAll(folder)
+
+	c := NewCorpus()
+	if err := c.Load(folder, regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	s := NewSharded(&c, BySubfolder(folder), func() *Model {
+		m := NewLSIModelWith(2, StopWords()...)
+		m.Collapse = 0.9
+		return m
+	})
+	if err := s.Train(); err != nil {
+		t.Fatalf("error training shards %s", err.Error())
+	}
+
+	qr := s.Query("national park", 5, 0.3)
+	if qr.Err != nil {
+		t.Fatalf("error querying shards %s", qr.Err.Error())
+	}
+	if len(qr.Matched) == 0 || len(qr.Versions) != len(qr.Matched) || len(qr.Versions[0]) != 1 {
+		t.Fatalf("expected copies of the park in both shards to collapse into 1 match, got: %v %v", qr.Matched, qr.Versions)
+	}
+	for _, doc := range qr.Matched[1:] {
+		if strings.HasPrefix(filepath.Base(c.GetPath(doc)), "park") {
+			t.Errorf("expected the park to match once, got: %v", qr.Matched)
+		}
+	}
+	if paths := []string{c.GetPath(qr.Matched[0]), c.GetPath(qr.Versions[0][0])}; filepath.Base(paths[0]) == filepath.Base(paths[1]) {
+		t.Errorf("expected the other copy as a version of the match, got: %v", paths)
+	}
+}
+
+func TestBands(t *testing.T) {
+	for _, distance := range []int{0, 6, 63} {
+		masks := bands(distance)
+		if len(masks) != distance+1 {
+			t.Errorf("expected %d bands, got: %d", distance+1, len(masks))
+		}
+		var all uint64
+		for _, mask := range masks {
+			if mask == 0 || all&mask != 0 {
+				t.Errorf("expected disjoint non empty bands, got: %x", masks)
+			}
+			all |= mask
+		}
+		if all != ^uint64(0) {
+			t.Errorf("expected bands to cover all bits, got: %x", all)
+		}
+	}
+	if masks := bands(64); len(masks) != 1 || masks[0] != 0 {
+		t.Errorf("expected a single band matching everything, got: %x", masks)
+	}
+	if maxDistance(0.9) != 6 || maxDistance(1.0) != 0 || maxDistance(0) != 64 {
+		t.Errorf("expected distances 6, 0 and 64, got: %d %d %d", maxDistance(0.9), maxDistance(1.0), maxDistance(0))
+	}
+}
@@ -0,0 +1,117 @@
+package nlp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultEncoding is the legacy encoding of files which are not valid UTF-8 and have no byte order mark, covering Latin-1
+const DefaultEncoding = "windows-1252"
+
+// boms are byte order marks of Unicode encodings, UTF-32 ones first as they start with UTF-16 ones
+var boms = []struct {
+	mark     []byte
+	name     string
+	encoding encoding.Encoding
+}{
+	{[]byte{0x00, 0x00, 0xFE, 0xFF}, "utf-32be", utf32.UTF32(utf32.BigEndian, utf32.IgnoreBOM)},
+	{[]byte{0xFF, 0xFE, 0x00, 0x00}, "utf-32le", utf32.UTF32(utf32.LittleEndian, utf32.IgnoreBOM)},
+	{[]byte{0xEF, 0xBB, 0xBF}, "utf-8", unicode.UTF8},
+	{[]byte{0xFE, 0xFF}, "utf-16be", unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)},
+	{[]byte{0xFF, 0xFE}, "utf-16le", unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)},
+}
+
+// errBinary tells content is binary rather than text in any encoding
+var errBinary = fmt.Errorf("binary content")
+
+// decode transcodes content to UTF-8 normalised to the filter's form, returning the name of its detected encoding.
+// Encoding is told by byte order mark, by NUL bytes of ASCII text in UTF-16, or by validity as UTF-8, falling back
+// to the filter's legacy encoding. Content with a NUL byte in no such encoding is binary
+func decode(content []byte, filter Filter) (string, string, error) {
+	name, enc, bom := detectEncoding(content)
+	if enc == nil {
+		if isBinary(content) {
+			return "", "", errBinary
+		}
+		if utf8.Valid(content) {
+			return normaliseText(string(content), filter.Normalisation), "utf-8", nil
+		}
+
+		name = filter.Encoding
+		if name == "" {
+			name = DefaultEncoding
+		}
+		var err error
+		if enc, err = htmlindex.Get(name); err != nil {
+			return "", "", fmt.Errorf("unknown encoding %q", name)
+		}
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(content[bom:])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid %s: %s", name, err)
+	}
+	// decoders replace invalid sequences, while valid content does not need replacing
+	if bytes.Count(decoded, []byte(string(utf8.RuneError))) > bytes.Count(content, []byte(string(utf8.RuneError))) {
+		return "", "", fmt.Errorf("invalid %s", name)
+	}
+	return normaliseText(string(decoded), filter.Normalisation), name, nil
+}
+
+// detectEncoding returns the Unicode encoding of content with a byte order mark, along with its length,
+// or of UTF-16 content without one having every other byte NUL, or nil encoding otherwise
+func detectEncoding(content []byte) (string, encoding.Encoding, int) {
+	for _, bom := range boms {
+		if bytes.HasPrefix(content, bom.mark) {
+			return bom.name, bom.encoding, len(bom.mark)
+		}
+	}
+
+	sniffed := content
+	if len(sniffed) > binarySniffLength {
+		sniffed = sniffed[:binarySniffLength]
+	}
+	pairs := len(sniffed) / 2
+	if pairs == 0 || len(content)%2 != 0 {
+		return "", nil, 0
+	}
+	even, odd := 0, 0
+	for i := 0; i+1 < len(sniffed); i += 2 {
+		if sniffed[i] == 0 {
+			even++
+		}
+		if sniffed[i+1] == 0 {
+			odd++
+		}
+	}
+	switch {
+	case odd*10 > pairs*9 && even == 0:
+		return "utf-16le", unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), 0
+	case even*10 > pairs*9 && odd == 0:
+		return "utf-16be", unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), 0
+	}
+	return "", nil, 0
+}
+
+// normaliseText applies Unicode normalisation form "NFC", "NFKC", "NFD" or "NFKD" to the text, NFC by default, or none for "none"
+func normaliseText(text string, form string) string {
+	switch strings.ToUpper(form) {
+	case "", "NFC":
+		return norm.NFC.String(text)
+	case "NFKC":
+		return norm.NFKC.String(text)
+	case "NFD":
+		return norm.NFD.String(text)
+	case "NFKD":
+		return norm.NFKD.String(text)
+	}
+	return text
+}
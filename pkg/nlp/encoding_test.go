@@ -0,0 +1,75 @@
+package nlp
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		filter   Filter
+		text     string
+		encoding string
+		err      bool
+	}{
+		{"utf-8", []byte("café"), Filter{}, "café", "utf-8", false},
+		{"utf-8 bom", []byte("\xEF\xBB\xBFcafé"), Filter{}, "café", "utf-8", false},
+		{"utf-16le bom", []byte("\xFF\xFEc\x00a\x00f\x00\xE9\x00"), Filter{}, "café", "utf-16le", false},
+		{"utf-16be bom", []byte("\xFE\xFF\x00c\x00a\x00f\x00\xE9"), Filter{}, "café", "utf-16be", false},
+		{"utf-16le", []byte("w\x00i\x00l\x00d\x00"), Filter{}, "wild", "utf-16le", false},
+		{"utf-32le bom", []byte("\xFF\xFE\x00\x00c\x00\x00\x00"), Filter{}, "c", "utf-32le", false},
+		{"latin-1", []byte("caf\xE9 cr\xE8me"), Filter{}, "café crème", "windows-1252", false},
+		{"shift_jis", []byte("\x93\xFA\x96\x7B"), Filter{Encoding: "shift_jis"}, "日本", "shift_jis", false},
+		{"nfc", []byte("café"), Filter{}, "café", "utf-8", false},
+		{"nfkc", []byte("ﬁle Ａ"), Filter{Normalisation: "NFKC"}, "file A", "utf-8", false},
+		{"none", []byte("café"), Filter{Normalisation: "none"}, "café", "utf-8", false},
+		{"binary", []byte("\x00\x01\x02\x03\x04"), Filter{}, "", "", true},
+		{"lone surrogate", []byte("\xFF\xFE\x00\xD8a\x00"), Filter{}, "", "", true},
+	}
+	for _, test := range tests {
+		text, encoding, err := decode(test.content, test.filter)
+		if (err != nil) != test.err {
+			t.Errorf("%s: expected error %v, got: %v", test.name, test.err, err)
+			continue
+		}
+		if text != test.text || encoding != test.encoding {
+			t.Errorf("%s: expected %q in %s, got: %q in %s", test.name, test.text, test.encoding, text, encoding)
+		}
+	}
+}
+
+func TestLoadEncodings(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"latin.txt":  "caf\xE9",
+		"utf16.txt":  "\xFF\xFEc\x00a\x00f\x00\xE9\x00",
+		"broken.txt": "\xFF\xFE\x00\xD8a\x00",
+	})
+	defer os.RemoveAll(dir)
+
+	c := NewCorpus()
+	if err := c.LoadWith(dir, Filter{}); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 documents, got: %v", c.Paths())
+	}
+	for i, encoding := range []string{"windows-1252", "utf-16le"} {
+		if got := c.GetMetadata(i)["encoding"]; got != encoding {
+			t.Errorf("expected %q to be decoded from %s, got: %s", c.GetPath(i), encoding, got)
+		}
+	}
+
+	c.Release()
+	if text, err := c.Read(1); err != nil || text != "café" {
+		t.Errorf("expected released document to be decoded again, got: %q %v", text, err)
+	}
+	if skipped := c.Skipped(); len(skipped) != 1 || skipped[0].Reason != SkipUndecodable {
+		t.Errorf("expected broken file to be skipped as undecodable, got: %v", skipped)
+	}
+
+	if err := c.LoadWith(dir, Filter{Encoding: "klingon"}); err == nil {
+		t.Errorf("expected error loading with unknown encoding")
+	}
+}
@@ -0,0 +1,109 @@
+package nlp
+
+import (
+	"io"
+	"math"
+	"strings"
+
+	"github.com/james-bowman/nlp"
+)
+
+// LoadSynonyms reads Solr-style synonym rules applied to queries
+func (m *Model) LoadSynonyms(r io.Reader) error {
+	s, err := parseSynonyms(r, m.tokenise)
+	if err != nil {
+		return err
+	}
+	m.synonyms = s
+	m.changed()
+	return nil
+}
+
+// tokenise splits text into terms the same way the vectoriser does
+func (m *Model) tokenise(text string) []string {
+	if vectoriser, ok := m.Pipeline.Vectoriser.(*nlp.CountVectoriser); ok {
+		return vectoriser.Tokeniser.Tokenise(text)
+	}
+	return strings.Fields(strings.ToLower(text))
+}
+
+// expand rewrites query with synonyms and adds terms closest to query's terms in LSI term space,
+// returning text to vectorise along with added terms
+func (m *Model) expand(q string) (string, []string) {
+	if m.synonyms == nil && m.Expansion < 1 {
+		return q, nil
+	}
+
+	tokens := m.tokenise(q)
+	expansions := make([]string, 0)
+	if m.synonyms != nil {
+		tokens, expansions = m.synonyms.rewrite(tokens)
+	}
+
+	if m.Expansion > 0 && m.termVectors != nil {
+		seen := make(map[string]bool)
+		for _, token := range tokens {
+			seen[token] = true
+		}
+		vocabulary := m.Vocabulary()
+		for _, token := range append([]string(nil), tokens...) {
+			for _, term := range m.nearestTerms(token, vocabulary, m.Expansion) {
+				if !seen[term] {
+					seen[term] = true
+					tokens = append(tokens, term)
+					expansions = append(expansions, term)
+				}
+			}
+		}
+	}
+
+	return strings.Join(tokens, " "), expansions
+}
+
+// nearestTerms returns up to n terms most similar to the given one in LSI term space
+func (m *Model) nearestTerms(term string, vocabulary []string, n int) []string {
+	vectoriser, ok := m.Pipeline.Vectoriser.(*nlp.CountVectoriser)
+	if !ok {
+		return nil
+	}
+	i, ok := vectoriser.Vocabulary[term]
+	if !ok || i >= len(m.termVectors) {
+		return nil
+	}
+
+	similarities := make([]float64, len(m.termVectors))
+	for j, v := range m.termVectors {
+		if j != i {
+			similarities[j] = dot(m.termVectors[i], v)
+		}
+	}
+	return topN(vocabulary, similarities, n)
+}
+
+// computeTermVectors scales terms' SVD components by singular values and normalises them
+func (m *Model) computeTermVectors() {
+	m.termVectors = nil
+	components := m.components()
+	if m.Expansion < 1 || components == nil {
+		return
+	}
+
+	dims, docs := m.Matrix.Dims()
+	singular := make([]float64, dims)
+	for k := range singular {
+		for j := 0; j < docs; j++ {
+			singular[k] += m.Matrix.At(k, j) * m.Matrix.At(k, j)
+		}
+		singular[k] = math.Sqrt(singular[k])
+	}
+
+	rows, _ := components.Dims()
+	m.termVectors = make([][]float64, rows)
+	for i := range m.termVectors {
+		v := make([]float64, dims)
+		for k := range v {
+			v[k] = components.At(i, k) * singular[k]
+		}
+		m.termVectors[i] = normalise(v)
+	}
+}
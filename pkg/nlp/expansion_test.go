@@ -0,0 +1,60 @@
+package nlp
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSynonymsRewrite(t *testing.T) {
+	s, err := parseSynonyms(strings.NewReader(`# comment
+couch, sofa, divan
+i-pod, i pod => ipod
+car => automobile, motor car
+`), strings.Fields)
+	if err != nil {
+		t.Fatalf("error parsing synonyms: %s", err)
+	}
+
+	tokens, added := s.rewrite(strings.Fields("red sofa"))
+	if want := []string{"red", "sofa", "couch", "divan"}; !reflect.DeepEqual(want, tokens) {
+		t.Errorf("expected equivalent synonyms to be added, got: %v", tokens)
+	}
+	if want := []string{"couch", "divan"}; !reflect.DeepEqual(want, added) {
+		t.Errorf("expected added synonyms to be reported, got: %v", added)
+	}
+
+	tokens, _ = s.rewrite(strings.Fields("my i pod and car"))
+	if want := []string{"my", "ipod", "and", "automobile", "motor", "car"}; !reflect.DeepEqual(want, tokens) {
+		t.Errorf("expected explicit mappings to replace phrases, got: %v", tokens)
+	}
+
+	if _, err := parseSynonyms(strings.NewReader("a => b => c"), strings.Fields); err == nil {
+		t.Errorf("expected error for more than one mapping")
+	}
+}
+
+func TestQueryExpansion(t *testing.T) {
+	c := NewCorpus()
+	if err := c.Load("../../books", regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	m := NewLSIModel()
+	m.Expansion = 2
+	if err := m.Train(&c); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+
+	if err := m.LoadSynonyms(strings.NewReader("getaway => mountain climbing")); err != nil {
+		t.Fatalf("error loading synonyms %s", err.Error())
+	}
+
+	qr := m.Query("getaway", 1, 0.3)
+	if len(qr.Matched) != 1 || c.GetPath(qr.Matched[0]) != "../../books/Grand Teton National Park.txt" {
+		t.Errorf("expected synonyms to match Grand Teton National Park, got: %v", qr.Matched)
+	}
+	if len(qr.Expansions) < 2 || len(qr.Expansions) > 5 || qr.Expansions[0] != "mountain climbing" {
+		t.Errorf("expected synonym followed by up to 2 closest terms per each of its terms, got: %v", qr.Expansions)
+	}
+}
@@ -0,0 +1,108 @@
+package nlp
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/james-bowman/nlp"
+	"gonum.org/v1/gonum/mat"
+)
+
+// TermExplanation describes a term of the query: whether it is in the vocabulary,
+// its TF-IDF weight in the query and its projection onto each of LSI components
+type TermExplanation struct {
+	Term         string
+	InVocabulary bool
+	StopWord     bool
+	Weight       float64
+	Projections  []float64
+}
+
+// ComponentExplanation holds query's and document's values of an LSI component,
+// along with the component's contribution to their cosine similarity
+type ComponentExplanation struct {
+	Query        float64
+	Document     float64
+	Contribution float64
+}
+
+// Explanation breaks down the similarity of the query and the document, which is the sum of components' contributions
+type Explanation struct {
+	Query      string
+	Document   int
+	Terms      []TermExplanation
+	Components []ComponentExplanation
+	Similarity float64
+}
+
+// Explain scores the document against the query stage by stage, showing how the similarity comes about
+func (m *Model) Explain(q string, doc int) (Explanation, error) {
+	_, docs := m.Matrix.Dims()
+	if doc < 0 || doc >= docs {
+		return Explanation{}, fmt.Errorf("document %d out of range [0, %d)", doc, docs)
+	}
+
+	last := len(m.Pipeline.Transformers) - 1
+	if last < 0 {
+		return Explanation{}, fmt.Errorf("pipeline has no reduction stage to explain")
+	}
+
+	text, _ := m.expand(q)
+	weighted, err := m.Pipeline.Vectoriser.Transform(text)
+	if err != nil {
+		return Explanation{}, err
+	}
+	for _, transformer := range m.Pipeline.Transformers[:last] {
+		if weighted, err = transformer.Transform(weighted); err != nil {
+			return Explanation{}, err
+		}
+	}
+	projected, err := m.Pipeline.Transformers[last].Transform(weighted)
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	e := Explanation{Query: q, Document: doc, Terms: make([]TermExplanation, 0)}
+
+	// query terms, in order of their first appearance
+	vectoriser, _ := m.Pipeline.Vectoriser.(*nlp.CountVectoriser)
+	components := m.components()
+	seen := make(map[string]bool)
+	for _, term := range m.tokenise(text) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		t := TermExplanation{Term: term}
+		if vectoriser != nil {
+			i, known := vectoriser.Vocabulary[term]
+			t.InVocabulary, t.StopWord = known, vectoriser.StopWords[term]
+			if known {
+				t.Weight = weighted.At(i, 0)
+				if components != nil {
+					_, k := components.Dims()
+					t.Projections = make([]float64, k)
+					for j := range t.Projections {
+						t.Projections[j] = t.Weight * components.At(i, j)
+					}
+				}
+			}
+		}
+		e.Terms = append(e.Terms, t)
+	}
+
+	// components' contributions to the cosine similarity
+	query, document := mat.Col(nil, 0, projected), mat.Col(nil, doc, m.Matrix)
+	norms := math.Sqrt(dot(query, query)) * math.Sqrt(dot(document, document))
+	e.Components = make([]ComponentExplanation, len(query))
+	for i := range query {
+		e.Components[i] = ComponentExplanation{Query: query[i], Document: document[i]}
+		if norms != 0 {
+			e.Components[i].Contribution = query[i] * document[i] / norms
+			e.Similarity += e.Components[i].Contribution
+		}
+	}
+
+	return e, nil
+}
@@ -0,0 +1,46 @@
+package nlp
+
+import (
+	"math"
+	"regexp"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	c := NewCorpus()
+	if err := c.Load("../../books", regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	m := NewLSIModel()
+	if err := m.Train(&c); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+
+	qr := m.Query("wild weekend", 1, 0.0)
+	e, err := m.Explain("wild weekend", qr.Matched[0])
+	if err != nil {
+		t.Fatalf("error explaining query %s", err.Error())
+	}
+
+	if math.Abs(e.Similarity-qr.Similarities[0]) > 1e-9 {
+		t.Errorf("expected explained similarity %f, got: %f", qr.Similarities[0], e.Similarity)
+	}
+	if len(e.Terms) != 2 || e.Terms[0].Term != "wild" || e.Terms[1].Term != "weekend" {
+		t.Fatalf("expected terms wild and weekend, got: %v", e.Terms)
+	}
+	if !e.Terms[0].InVocabulary || e.Terms[0].Weight <= 0 || len(e.Terms[0].Projections) != len(e.Components) {
+		t.Errorf("expected weighted and projected wild, got: %v", e.Terms[0])
+	}
+	if e.Terms[1].InVocabulary || e.Terms[1].Weight != 0 {
+		t.Errorf("expected weekend out of vocabulary, got: %v", e.Terms[1])
+	}
+	for i, component := range e.Components {
+		if math.Abs(component.Query-e.Terms[0].Projections[i]) > 1e-9 {
+			t.Errorf("expected component %d of the query to be projection of its only known term, got: %f", i, component.Query)
+		}
+	}
+
+	if _, err := m.Explain("wild", 4); err == nil {
+		t.Errorf("expected error for unknown document")
+	}
+}
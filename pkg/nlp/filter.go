@@ -0,0 +1,93 @@
+package nlp
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// IgnoreFile lists gitignore style patterns of paths left out of the corpus, relative to the directory it is in
+const IgnoreFile = ".qdoxignore"
+
+// binarySniffLength is the number of leading bytes of files searched for NUL bytes marking binary files
+const binarySniffLength = 8000
+
+// Reasons of skipping files
+const (
+	SkipExcluded    = "excluded"
+	SkipIgnored     = "ignored"
+	SkipHidden      = "hidden"
+	SkipTooLarge    = "too large"
+	SkipBinary      = "binary"
+	SkipUndecodable = "undecodable"
+)
+
+// Filter decides which files under the loaded folder become documents of the corpus, and how they are decoded.
+// Files listed in IgnoreFile of any directory, binary files and files which fail to decode are always skipped
+type Filter struct {
+	// Pattern is the regular expression paths of documents should match, nil matches all of them
+	Pattern *regexp.Regexp
+	// Exclude lists gitignore style patterns of paths relative to the loaded folder, skipped along with contents of directories
+	Exclude []string
+	// MaxSize is the size in bytes above which files are skipped, 0 does not limit it
+	MaxSize int64
+	// Hidden loads directories with names starting with a dot, skipped by default
+	Hidden bool
+	// Archives loads members of zip and tar archives, optionally gzipped, matching the pattern by their virtual paths
+	// such as bundle.zip!/dir/file.txt, skipping archives themselves
+	Archives bool
+	// Encoding names legacy encoding of files which are neither valid UTF-8 nor marked as Unicode, DefaultEncoding if empty
+	Encoding string
+	// Normalisation is the Unicode normalisation form of documents, "NFC" if empty, "NFKC" also folds compatibility
+	// characters such as ligatures and full width letters, "none" keeps them as they are
+	Normalisation string
+}
+
+// Skipped is a file or an archive member matching the pattern, a directory, or an unreadable archive, left out of the corpus,
+// along with the reason
+type Skipped struct {
+	Path   string
+	Reason string
+}
+
+// inHiddenDirectory tells if any directory of the slash separated path has a name starting with a dot,
+// skipping members of archives and repositories like hidden directories of the folder, while hidden files are loaded
+func inHiddenDirectory(name string) bool {
+	for _, dir := range strings.Split(path.Dir(name), "/") {
+		if strings.HasPrefix(dir, ".") && dir != "." && dir != ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the encoding and the normalisation form are known
+func (f Filter) Validate() error {
+	if f.Encoding != "" {
+		if _, err := htmlindex.Get(f.Encoding); err != nil {
+			return fmt.Errorf("unknown encoding %q", f.Encoding)
+		}
+	}
+	switch strings.ToUpper(f.Normalisation) {
+	case "", "NFC", "NFKC", "NFD", "NFKD", "NONE":
+	default:
+		return fmt.Errorf("unknown normalisation form %q", f.Normalisation)
+	}
+	return nil
+}
+
+func (f Filter) matches(path string) bool {
+	return f.Pattern == nil || f.Pattern.MatchString(path)
+}
+
+// isBinary tells whether content is binary rather than text by a NUL byte among its leading bytes
+func isBinary(content []byte) bool {
+	if len(content) > binarySniffLength {
+		content = content[:binarySniffLength]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
@@ -0,0 +1,292 @@
+package nlp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/stormcrows/qdox/pkg/ignore"
+)
+
+// RevisionSeparator separates path of a file in a git repository from abbreviated hash of its revision, e.g. docs/design.md@3f2a1bc
+const RevisionSeparator = "@"
+
+// revision is content of a file of the repository, along with the oldest of commits having it
+type revision struct {
+	name   string
+	blob   plumbing.Hash
+	size   int64
+	commit *object.Commit
+	latest bool
+}
+
+// LoadRepository replaces documents of the corpus with files of the git repository in root folder passing the filter,
+// as of given ref, HEAD if empty, or with all their past revisions too if history is set.
+// Documents have paths of files followed by RevisionSeparator and abbreviated hash of the commit introducing their revision,
+// and metadata noting the commit, its author, date and subject
+func (c *Corpus) LoadRepository(root string, ref string, history bool, filter Filter) error {
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	repo, err := git.PlainOpenWithOptions(root, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return err
+	}
+
+	// files of the repository are loaded from the root folder only, which may be its subfolder
+	prefix := ""
+	if worktree, err := repo.Worktree(); err == nil {
+		top, err := filepath.Abs(worktree.Filesystem.Root())
+		if err != nil {
+			return err
+		}
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return err
+		}
+		if prefix, err = filepath.Rel(top, abs); err != nil {
+			return err
+		}
+		if prefix = filepath.ToSlash(prefix); prefix == "." {
+			prefix = ""
+		}
+	}
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return err
+	}
+	head, err := repo.CommitObject(*hash)
+	if err != nil {
+		return err
+	}
+	tree, err := head.Tree()
+	if err != nil {
+		return err
+	}
+	excluded, err := ignore.Parse(root, strings.NewReader(strings.Join(filter.Exclude, "\n")))
+	if err != nil {
+		return err
+	}
+	ignored, err := treeIgnoreRules(root, prefix, tree)
+	if err != nil {
+		return err
+	}
+
+	stored, err := c.openStored(filter)
+	if err != nil {
+		return err
+	}
+	skipped := make([]Skipped, 0)
+	// reasons of skipping files by their names, empty for loaded ones
+	reasons := make(map[string]string)
+	revisions := make(map[revision]*revision)
+	ordered := make([]*revision, 0)
+
+	// history of the ref tells which commits introduced revisions of files, the latest ones are the ref's
+	log, err := repo.Log(&git.LogOptions{From: *hash})
+	if err != nil {
+		return err
+	}
+	i := 0
+	err = log.ForEach(func(commit *object.Commit) error {
+		defer func() { i++ }()
+		if !history && i > 0 {
+			return resolveRevisions(commit, prefix, revisions)
+		}
+
+		tree, err := commit.Tree()
+		if err != nil {
+			return err
+		}
+		return tree.Files().ForEach(func(f *object.File) error {
+			name, ok := relativeName(prefix, f.Name)
+			if !ok || f.Mode == filemode.Symlink || f.Mode == filemode.Submodule {
+				return nil
+			}
+			p := filepath.Join(root, filepath.FromSlash(name))
+			if !filter.matches(p) {
+				return nil
+			}
+
+			key := revision{name: name, blob: f.Hash}
+			if r, ok := revisions[key]; ok {
+				r.commit = commit
+				return nil
+			}
+			reason, decided := reasons[name]
+			if !decided {
+				if skip, _ := excluded.Match(p, false); skip {
+					reason = SkipExcluded
+				} else if ignored.Ignored(p, false) {
+					reason = SkipIgnored
+				} else if !filter.Hidden && inHiddenDirectory(name) {
+					reason = SkipHidden
+				}
+				if reason != "" {
+					skipped = append(skipped, Skipped{p, reason})
+				}
+				reasons[name] = reason
+			}
+			if reason != "" {
+				return nil
+			}
+
+			r := &revision{name: name, blob: f.Hash, size: f.Size, commit: commit, latest: i == 0}
+			revisions[key] = r
+			ordered = append(ordered, r)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	documents := make([]Document, 0, len(ordered))
+	for _, r := range ordered {
+		p := filepath.Join(root, filepath.FromSlash(r.name)) + RevisionSeparator + r.commit.Hash.String()[:7]
+		if filter.MaxSize > 0 && r.size > filter.MaxSize {
+			skipped = append(skipped, Skipped{p, SkipTooLarge})
+			continue
+		}
+
+		// revisions never change, stored ones are reused, updating those which stopped being the latest
+		read := blobReader(repo, r.blob)
+		if doc, ok := stored.get(p, r.size, r.commit.Author.When); ok {
+			if latest := history && r.latest; latest != (doc.metadata["latest"] == "true") {
+				delete(doc.metadata, "latest")
+				if latest {
+					doc.metadata["latest"] = "true"
+				}
+				stored.put(&doc, r.size, r.commit.Author.When)
+			}
+			doc.read = read
+			documents = append(documents, doc)
+			continue
+		}
+		content, err := read()
+		if err != nil {
+			return err
+		}
+		doc, reason := decodeDocument(p, content, filter)
+		if reason != "" {
+			skipped = append(skipped, Skipped{p, reason})
+			continue
+		}
+
+		doc.read = read
+		doc.metadata["size"] = fmt.Sprint(r.size)
+		doc.metadata["modified"] = r.commit.Author.When.UTC().Format(time.RFC3339)
+		doc.metadata["commit"] = r.commit.Hash.String()
+		doc.metadata["author"] = r.commit.Author.Name
+		doc.metadata["subject"] = strings.SplitN(strings.TrimSpace(r.commit.Message), "\n", 2)[0]
+		if history && r.latest {
+			doc.metadata["latest"] = "true"
+		}
+		stored.put(&doc, r.size, r.commit.Author.When)
+		documents = append(documents, doc)
+	}
+	if err := stored.save(documents, filter); err != nil {
+		return err
+	}
+
+	c.documents, c.skipped, c.filter, c.reused = documents, skipped, filter, stored.reused
+	return nil
+}
+
+// resolveRevisions looks up files of unresolved revisions in the commit, which has them if their files have the same blobs,
+// and resolves those it does not have, so that commits introducing revisions of the ref are found without listing whole trees.
+// It stops the log once all of them are resolved
+func resolveRevisions(commit *object.Commit, prefix string, unresolved map[revision]*revision) error {
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	for key, r := range unresolved {
+		entry, err := tree.FindEntry(path.Join(prefix, r.name))
+		if err != nil && err != object.ErrEntryNotFound && err != object.ErrDirectoryNotFound {
+			return err
+		}
+		if err != nil || entry.Hash != r.blob {
+			delete(unresolved, key)
+			continue
+		}
+		r.commit = commit
+	}
+	if len(unresolved) == 0 {
+		return storer.ErrStop
+	}
+	return nil
+}
+
+// relativeName returns slash separated name of the file of the repository relative to the prefix, if it is under it
+func relativeName(prefix string, name string) (string, bool) {
+	if prefix == "" {
+		return name, true
+	}
+	if !strings.HasPrefix(name, prefix+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(name, prefix+"/"), true
+}
+
+// treeIgnoreRules parses ignore files of the tree under the prefix, shallower ones first
+func treeIgnoreRules(root string, prefix string, tree *object.Tree) (ignore.Matcher, error) {
+	files := make([]*object.File, 0)
+	err := tree.Files().ForEach(func(f *object.File) error {
+		if path.Base(f.Name) == IgnoreFile {
+			files = append(files, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(files, func(i, j int) bool { return strings.Count(files[i].Name, "/") < strings.Count(files[j].Name, "/") })
+
+	matcher := ignore.Matcher{}
+	for _, f := range files {
+		name, ok := relativeName(prefix, f.Name)
+		if !ok {
+			continue
+		}
+		content, err := f.Contents()
+		if err != nil {
+			return nil, err
+		}
+		rules, err := ignore.Parse(filepath.Join(root, filepath.FromSlash(path.Dir(name))), strings.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		matcher = append(matcher, rules)
+	}
+	return matcher, nil
+}
+
+// blobReader returns function reading content of the blob
+func blobReader(repo *git.Repository, hash plumbing.Hash) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		blob, err := repo.BlobObject(hash)
+		if err != nil {
+			return nil, err
+		}
+		r, err := blob.Reader()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	}
+}
@@ -0,0 +1,108 @@
+package nlp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestLoadRepository(t *testing.T) {
+	dir := writeFiles(t, nil)
+	defer os.RemoveAll(dir)
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := make([]string, 0)
+	commit := func(files map[string]string, message string, day int) {
+		writeFilesTo(t, dir, files)
+		for name := range files {
+			if _, err := worktree.Add(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		when := time.Date(2019, 7, day, 12, 0, 0, 0, time.UTC)
+		hash, err := worktree.Commit(message, &git.CommitOptions{Author: &object.Signature{Name: "Jane", Email: "jane@example.com", When: when}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, hash.String())
+	}
+	commit(map[string]string{"design.txt": "geysers of yellowstone", "notes.txt": "grizzly bears", ".hidden/x.txt": "hidden"}, "Add design\n\nWith notes", 1)
+	commit(map[string]string{"design.txt": "glaciers of grand teton"}, "Rewrite design", 2)
+
+	c := NewCorpus()
+	filter := Filter{Pattern: regexp.MustCompile("\\.txt$")}
+	if err := c.LoadRepository(dir, "", false, filter); err != nil {
+		t.Fatalf("error loading repository %s", err.Error())
+	}
+	want := []string{
+		filepath.Join(dir, "design.txt") + "@" + hashes[1][:7],
+		filepath.Join(dir, "notes.txt") + "@" + hashes[0][:7],
+	}
+	if got := c.Paths(); !reflect.DeepEqual(want, got) {
+		t.Errorf("expected files at HEAD %v, got: %v", want, got)
+	}
+	wantMetadata := map[string]string{
+		"size":     "13",
+		"modified": "2019-07-01T12:00:00Z",
+		"commit":   hashes[0],
+		"author":   "Jane",
+		"subject":  "Add design",
+	}
+	if got := c.GetMetadata(1); !reflect.DeepEqual(wantMetadata, got) {
+		t.Errorf("expected metadata of the revision %v, got: %v", wantMetadata, got)
+	}
+	if skipped := c.Skipped(); len(skipped) != 1 || skipped[0].Reason != SkipHidden {
+		t.Errorf("expected hidden file to be skipped, got: %v", skipped)
+	}
+
+	if err := c.LoadRepository(dir, "", true, filter); err != nil {
+		t.Fatalf("error loading repository %s", err.Error())
+	}
+	want = append(want, filepath.Join(dir, "design.txt")+"@"+hashes[0][:7])
+	if got := c.Paths(); !reflect.DeepEqual(want, got) {
+		t.Errorf("expected all revisions %v, got: %v", want, got)
+	}
+	if c.GetMetadata(0)["latest"] != "true" || c.GetMetadata(2)["latest"] != "" {
+		t.Errorf("expected only revisions at HEAD to be latest, got: %v", c.GetMetadata(2))
+	}
+
+	c.Release()
+	if text, err := c.Read(2); err != nil || text != "geysers of yellowstone" {
+		t.Errorf("expected released revision to be read from the repository, got: %q %v", text, err)
+	}
+
+	if err := c.LoadRepository(dir, hashes[0], false, filter); err != nil {
+		t.Fatalf("error loading repository %s", err.Error())
+	}
+	if c.Len() != 2 || c.GetPath(0) != filepath.Join(dir, "design.txt")+"@"+hashes[0][:7] {
+		t.Errorf("expected files as of the first commit, got: %v", c.Paths())
+	}
+
+	// revisions of a subfolder are looked up in older commits by their paths in the repository
+	commit(map[string]string{"sub/plan.txt": "old faithful"}, "Add plan", 3)
+	commit(map[string]string{"design.txt": "jackson hole"}, "Rewrite design again", 4)
+	if err := c.LoadRepository(filepath.Join(dir, "sub"), "", false, filter); err != nil {
+		t.Fatalf("error loading repository %s", err.Error())
+	}
+	if want := []string{filepath.Join(dir, "sub", "plan.txt") + "@" + hashes[2][:7]}; !reflect.DeepEqual(want, c.Paths()) {
+		t.Errorf("expected revision of the subfolder %v, got: %v", want, c.Paths())
+	}
+
+	if err := c.LoadRepository(dir, "missing", false, filter); err == nil {
+		t.Errorf("expected error loading unknown ref")
+	}
+}
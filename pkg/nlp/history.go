@@ -0,0 +1,133 @@
+package nlp
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/stormcrows/qdox/pkg/store"
+	"gonum.org/v1/gonum/mat"
+)
+
+// History returns versions of the document for given document's index replaced since, oldest first,
+// followed by its current one which is not replaced. Versions are kept in the store of the corpus
+func (c *Corpus) History(i int) ([]store.Version, error) {
+	if c.store == nil {
+		return nil, fmt.Errorf("history of documents needs a store")
+	}
+	versions, err := c.store.Versions(c.documents[i].path)
+	if err != nil {
+		return nil, err
+	}
+	r, ok, err := c.store.Get(c.documents[i].path)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		versions = append(versions, store.Version{Record: r})
+	}
+	return versions, nil
+}
+
+// asOf returns indexes of documents of the corpus which existed at the time, along with past contents of those changed since,
+// which are the only ones looked up in the store, and versions of documents removed since, found by scanning all versions
+func (c *Corpus) asOf(t time.Time) ([]int, map[int]string, []store.Version, error) {
+	if c.store == nil {
+		return nil, nil, nil, fmt.Errorf("history of documents needs a store")
+	}
+	existing := make([]int, 0, len(c.documents))
+	past := make(map[int]string)
+	paths := make(map[string]bool, len(c.documents))
+	for i, doc := range c.documents {
+		paths[doc.path] = true
+		if !doc.since.After(t) {
+			existing = append(existing, i)
+			continue
+		}
+
+		versions, err := c.store.Versions(doc.path)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for j := len(versions) - 1; j >= 0; j-- {
+			if versions[j].Since.After(t) {
+				continue
+			}
+			if versions[j].Replaced.After(t) {
+				existing = append(existing, i)
+				past[i] = versions[j].Content
+			}
+			break
+		}
+	}
+
+	removed := make([]store.Version, 0)
+	err := c.store.ForEachVersion(func(v store.Version) error {
+		if !paths[v.Path] && !v.Since.After(t) && v.Replaced.After(t) {
+			removed = append(removed, v)
+		}
+		return nil
+	})
+	return existing, past, removed, err
+}
+
+// QueryAsOf returns documents matching given query as they were at the time, ranked by LSI similarity.
+// Past versions of documents changed or removed since are folded into the model's space, so their terms no longer in the vocabulary
+// do not count, and documents which did not exist yet are left out. Documents removed since are matched as -1, named by Paths
+func (m *Model) QueryAsOf(q string, n int, threshold float64, t time.Time) QueryResult {
+	existing, past, removed, err := m.Corpus.asOf(t)
+	if err != nil {
+		return QueryResult{Query: q, Err: err}
+	}
+
+	text, expansions := m.expand(q)
+	queryVector, err := m.Pipeline.Transform(text)
+	if err != nil {
+		return QueryResult{Query: q, Err: fmt.Errorf("Failed to process documents: %q", err.Error())}
+	}
+	query := mat.Col(nil, 0, queryVector)
+	similarities := m.score(query, existing)
+	scored := len(similarities)
+
+	// removed documents are scored after those of the corpus
+	docs := make([]int, 0, len(past)+len(removed))
+	for doc := range past {
+		docs = append(docs, doc)
+	}
+	sort.Ints(docs)
+	contents := make([]string, 0, cap(docs))
+	for _, doc := range docs {
+		contents = append(contents, past[doc])
+	}
+	for j, v := range removed {
+		docs = append(docs, scored+j)
+		existing = append(existing, scored+j)
+		contents = append(contents, v.Content)
+	}
+	similarities = append(similarities, make([]float64, len(removed))...)
+	if len(contents) > 0 {
+		vectors, err := m.Pipeline.Transform(contents...)
+		if err != nil {
+			return QueryResult{Query: q, Err: fmt.Errorf("Failed to process documents: %q", err.Error())}
+		}
+		query = normalise(query)
+		for j, doc := range docs {
+			similarities[doc] = dot(normalise(mat.Col(nil, j, vectors)), query)
+		}
+	}
+
+	matched, scores := topMatches(similarities, existing, threshold, n)
+	qr := QueryResult{Query: q, Matched: matched, Similarities: scores, Expansions: expansions}
+	if len(removed) > 0 {
+		qr.Paths = make([]string, len(matched))
+		for i, doc := range matched {
+			if j := doc - scored; j >= 0 {
+				qr.Matched[i], qr.Paths[i] = -1, removed[j].Path
+			} else {
+				qr.Paths[i] = m.Corpus.GetPath(doc)
+			}
+		}
+	}
+	qr.mean, qr.deviation = distribution(similarities, existing)
+	return qr
+}
@@ -0,0 +1,120 @@
+package nlp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stormcrows/qdox/pkg/store"
+)
+
+func TestQueryAsOf(t *testing.T) {
+	dir := writeFiles(t, nil)
+	defer os.RemoveAll(dir)
+
+	s, err := store.Open(filepath.Join(dir, "qdox.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// files are written a day apart, the park one is rewritten about football on the third day,
+	// when the park moves to another file keeping its terms in the vocabulary
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	write := func(name, content string, modified time.Time) string {
+		writeFilesTo(t, dir, map[string]string{name: content})
+		path := filepath.Join(dir, name)
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	book := func(name string) string {
+		content, err := ioutil.ReadFile(filepath.Join("../../books", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	park := write("park.txt", book("Grand Teton National Park.txt"), day(1))
+	sausage := write("sausage.txt", book("Butchers Packers and Sausage Makers Red Book.txt"), day(1))
+	write("king.txt", book("The Sword of the King - Ronald Macdonald.txt"), day(2))
+
+	c := NewCorpus()
+	c.UseStore(s)
+	filter := Filter{Pattern: regexp.MustCompile("\\.txt$")}
+	if err := c.LoadWith(dir, filter); err != nil {
+		t.Fatal(err)
+	}
+	write("park.txt", book("Around the End - Ralph Henry Barbour.txt"), day(3))
+	write("teton.txt", book("Grand Teton National Park.txt"), day(3))
+	if err := c.LoadWith(dir, filter); err != nil {
+		t.Fatal(err)
+	}
+	// the sausage one is removed since
+	if err := os.Remove(sausage); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadWith(dir, filter); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewLSIModel()
+	if err := m.Train(&c); err != nil {
+		t.Fatal(err)
+	}
+	doc := -1
+	for i, path := range c.Paths() {
+		if path == park {
+			doc = i
+		}
+	}
+
+	history, err := c.History(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || !history[0].Since.Equal(day(1)) || !history[0].Replaced.Equal(day(3)) || !history[1].Since.Equal(day(3)) || !history[1].Replaced.IsZero() {
+		t.Errorf("expected version of day 1 replaced on day 3 followed by the current one, got: %+v", history)
+	}
+
+	// the park was written about until the third day
+	for _, test := range []struct {
+		asOf      time.Time
+		documents int
+		park      bool
+	}{
+		{day(1), 2, true},
+		{day(2), 3, true},
+		{day(3), 4, false},
+	} {
+		qr := m.QueryAsOf("grand teton national park", 4, 0, test.asOf)
+		if qr.Err != nil {
+			t.Fatal(qr.Err)
+		}
+		if len(qr.Matched) != test.documents {
+			t.Errorf("expected %d documents as of %s, got: %v", test.documents, test.asOf, qr.Matched)
+		}
+		if len(qr.Matched) > 0 && (qr.Matched[0] == doc) != test.park {
+			t.Errorf("expected park ranked first as of %s to be %t, got: %v", test.asOf, test.park, qr.Matched)
+		}
+		removed := false
+		for i, v := range qr.Matched {
+			removed = removed || v == -1 && qr.Paths[i] == sausage
+		}
+		if !removed {
+			t.Errorf("expected removed sausage document as of %s, got: %v %v", test.asOf, qr.Matched, qr.Paths)
+		}
+	}
+
+	if qr := m.QueryAsOf("park", 3, 0, day(1).Add(-time.Hour)); len(qr.Matched) != 0 {
+		t.Errorf("expected no documents before the first one was written, got: %v", qr.Matched)
+	}
+	empty := NewCorpus()
+	if _, err := empty.History(0); err == nil {
+		t.Errorf("expected history without a store to fail")
+	}
+}
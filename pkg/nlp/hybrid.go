@@ -0,0 +1,150 @@
+package nlp
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	rrfK   = 60.0
+)
+
+// Fusion combines LSI cosine similarity of hybrid queries with their BM25 lexical score
+type Fusion struct {
+	// Method is "sum" for weighted sum of the scores, "rrf" for reciprocal rank fusion, or "" for LSI similarity only
+	Method string
+	// Semantic and Lexical weigh LSI and BM25 scores, or ranks in case of rrf
+	Semantic float64
+	Lexical  float64
+}
+
+// posting notes count of a term in the document
+type posting struct {
+	doc   int
+	count float64
+}
+
+// lexicalIndex holds postings of every term along with lengths of documents, scoring queries with BM25
+type lexicalIndex struct {
+	postings  [][]posting
+	lengths   []float64
+	avgLength float64
+}
+
+// Validate checks the method is known and weights are not negative
+func (f Fusion) Validate() error {
+	switch f.Method {
+	case "", "sum", "rrf":
+	default:
+		return fmt.Errorf("unknown fusion method %q", f.Method)
+	}
+	if f.Semantic < 0 || f.Lexical < 0 || (f.Method != "" && f.Semantic+f.Lexical == 0) {
+		return fmt.Errorf("fusion weights should be non-negative and not both zero")
+	}
+	return nil
+}
+
+// fuse combines the scores into similarities ranging from 0 to 1:
+// weighted sum divides BM25 scores by the highest one, and rrf divides by the score of a document ranked first by both
+func (f Fusion) fuse(semantic, lexical []float64) []float64 {
+	fused := make([]float64, len(semantic))
+	total := f.Semantic + f.Lexical
+
+	if f.Method == "rrf" {
+		semanticRanks, lexicalRanks := ranks(semantic), ranks(lexical)
+		for i := range fused {
+			if semanticRanks[i] > 0 {
+				fused[i] += f.Semantic / (rrfK + float64(semanticRanks[i]))
+			}
+			if lexicalRanks[i] > 0 {
+				fused[i] += f.Lexical / (rrfK + float64(lexicalRanks[i]))
+			}
+			fused[i] *= (rrfK + 1) / total
+		}
+		return fused
+	}
+
+	highest := 0.0
+	for _, s := range lexical {
+		highest = math.Max(highest, s)
+	}
+	for i := range fused {
+		fused[i] = f.Semantic * semantic[i]
+		if highest > 0 {
+			fused[i] += f.Lexical * lexical[i] / highest
+		}
+		fused[i] /= total
+	}
+	return fused
+}
+
+// ranks returns 1 based ranks of documents by their positive scores, 0 for the rest
+func ranks(scores []float64) []int {
+	idx := topIndexes(scores, len(scores))
+	r := make([]int, len(scores))
+	for rank, i := range idx {
+		r[i] = rank + 1
+	}
+	return r
+}
+
+// indexCounts builds lexical index of documents from the count matrix
+func (m *Model) indexCounts(counts mat.Matrix) {
+	rows, docs := counts.Dims()
+	index := &lexicalIndex{postings: make([][]posting, rows), lengths: make([]float64, docs)}
+
+	visit := func(i, j int, v float64) {
+		if v != 0 {
+			index.postings[i] = append(index.postings[i], posting{j, v})
+			index.lengths[j] += v
+		}
+	}
+
+	if sparse, ok := counts.(nonZeroDoer); ok {
+		sparse.DoNonZero(visit)
+	} else {
+		for i := 0; i < rows; i++ {
+			for j := 0; j < docs; j++ {
+				visit(i, j, counts.At(i, j))
+			}
+		}
+	}
+
+	for _, length := range index.lengths {
+		index.avgLength += length
+	}
+	if docs > 0 {
+		index.avgLength /= float64(docs)
+	}
+	// documents without terms have no postings, the length only keeps their normalisation a number
+	if index.avgLength == 0 {
+		index.avgLength = 1
+	}
+	m.lexical = index
+}
+
+// bm25 scores every document against term counts of the query
+func (m *Model) bm25(query mat.Matrix) []float64 {
+	index := m.lexical
+	scores := make([]float64, len(index.lengths))
+	docs := float64(len(index.lengths))
+
+	rows, _ := query.Dims()
+	for i := 0; i < rows && i < len(index.postings); i++ {
+		count := query.At(i, 0)
+		if count == 0 || len(index.postings[i]) == 0 {
+			continue
+		}
+		df := float64(len(index.postings[i]))
+		idf := math.Log(1 + (docs-df+0.5)/(df+0.5))
+		for _, p := range index.postings[i] {
+			norm := bm25K1 * (1 - bm25B + bm25B*index.lengths[p.doc]/index.avgLength)
+			scores[p.doc] += count * idf * p.count * (bm25K1 + 1) / (p.count + norm)
+		}
+	}
+	return scores
+}
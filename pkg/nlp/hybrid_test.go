@@ -0,0 +1,75 @@
+package nlp
+
+import (
+	"math"
+	"regexp"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestHybridQuery(t *testing.T) {
+	c := NewCorpus()
+	if err := c.Load("../../books", regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	m := NewLSIModel()
+	if err := m.Train(&c); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+
+	if qr := m.Query("national park", 5, 0.0); qr.Lexical != nil || qr.Semantic != nil {
+		t.Errorf("expected no component scores without fusion")
+	}
+
+	qr := m.QueryWith("national park", 5, 0.0, Fusion{"sum", 1, 1})
+	if qr.Err != nil {
+		t.Fatalf("error querying model %s", qr.Err.Error())
+	}
+	if len(qr.Semantic) != len(qr.Matched) || len(qr.Lexical) != len(qr.Matched) {
+		t.Fatalf("expected component scores of every result, got: %v %v", qr.Semantic, qr.Lexical)
+	}
+	if qr.Lexical[0] <= 0 {
+		t.Errorf("expected positive BM25 score of the best result, got: %f", qr.Lexical[0])
+	}
+	highest := 0.0
+	for _, s := range qr.Lexical {
+		highest = math.Max(highest, s)
+	}
+	for i := range qr.Matched {
+		if expected := (qr.Semantic[i] + qr.Lexical[i]/highest) / 2; math.Abs(expected-qr.Similarities[i]) > 1e-9 {
+			t.Errorf("expected weighted sum %f, got: %f", expected, qr.Similarities[i])
+		}
+	}
+
+	qr = m.QueryWith("national park", 1, 0.0, Fusion{"rrf", 1, 1})
+	if len(qr.Matched) != 1 || math.Abs(qr.Similarities[0]-1.0) > 1e-9 {
+		t.Errorf("expected document ranked first by both scores to fuse into 1, got: %v", qr.Similarities)
+	}
+
+	qr = m.QueryWith("national park", 5, 0.0, Fusion{"sum", 0, 1})
+	for i := range qr.Matched {
+		if qr.Lexical[i] == 0 && qr.Similarities[i] != 0 {
+			t.Errorf("expected lexical only similarity to follow BM25, got: %v", qr.Similarities)
+		}
+	}
+
+	for _, fusion := range []Fusion{{"max", 1, 1}, {"sum", -1, 1}, {"rrf", 0, 0}} {
+		if qr := m.QueryWith("national park", 5, 0.0, fusion); qr.Err == nil {
+			t.Errorf("expected error for fusion %v", fusion)
+		}
+	}
+}
+
+func TestIndexCountsWithoutTerms(t *testing.T) {
+	m := &Model{}
+	m.indexCounts(mat.NewDense(2, 3, nil))
+	if m.lexical.avgLength != 1 {
+		t.Errorf("expected average length of documents without terms to be 1, got: %f", m.lexical.avgLength)
+	}
+	for _, score := range m.bm25(mat.NewDense(2, 1, []float64{1, 1})) {
+		if math.IsNaN(score) || score != 0 {
+			t.Errorf("expected documents without terms to score 0, got: %f", score)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package nlp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/stormcrows/qdox/pkg/ann"
+	"gonum.org/v1/gonum/mat"
+)
+
+// buildIndex indexes LSI vectors of documents for approximate search, unless disabled or the corpus is small enough to scan
+func (m *Model) buildIndex() {
+	m.index = nil
+	_, docs := m.Matrix.Dims()
+	if m.ANN.M < 1 || docs < m.ANN.MinVectors {
+		return
+	}
+
+	index := ann.NewHNSW(m.ANN)
+	for i := 0; i < docs; i++ {
+		index.Add(m.normalised.RawRowView(i))
+	}
+	m.index = index
+}
+
+// candidates returns documents to score against the query vector: as many nearest neighbours found by the index
+// as its searches consider, but at least n, or nil to score all documents when there is no index
+func (m *Model) candidates(queryVector mat.Matrix, n int) []int {
+	if m.index == nil {
+		return nil
+	}
+
+	if m.ANN.EfSearch > n {
+		n = m.ANN.EfSearch
+	}
+	neighbours := m.index.Search(normalise(mat.Col(nil, 0, queryVector)), n)
+	ids := make([]int, len(neighbours))
+	for i, neighbour := range neighbours {
+		ids[i] = neighbour.ID
+	}
+	return ids
+}
+
+// Indexed tells whether queries search the approximate nearest neighbour index instead of scanning all documents
+func (m *Model) Indexed() bool {
+	return m.index != nil
+}
+
+// SaveIndex writes the approximate nearest neighbour index built at training, if any
+func (m *Model) SaveIndex(w io.Writer) error {
+	if m.index == nil {
+		return nil
+	}
+	return m.index.Encode(w)
+}
+
+// LoadIndex reads the index written by SaveIndex, replacing the one built at training
+// as long as it has as many vectors of as many dimensions as documents of the model
+func (m *Model) LoadIndex(r io.Reader) error {
+	index, err := ann.Decode(r)
+	if err != nil {
+		return err
+	}
+	dims, docs := m.Matrix.Dims()
+	if index.Len() != docs || (docs > 0 && index.Dims() != dims) {
+		return fmt.Errorf("index of %d vectors of %d dimensions does not match %d documents of %d dimensions",
+			index.Len(), index.Dims(), docs, dims)
+	}
+	m.index = index
+	m.changed()
+	return nil
+}
+
+// indexState is the key of state of the store holding the index built at training, followed by the shard of the corpus if any
+const indexState = "index"
+
+func indexKey(c *Corpus) string {
+	if c.shard == "" {
+		return indexState
+	}
+	return indexState + "/" + c.shard
+}
+
+// restoreIndex replaces building the index with the one kept in the store of the corpus by the last training,
+// if it was built with the same configuration from the same vectors of documents
+func (m *Model) restoreIndex(c *Corpus) bool {
+	_, docs := m.Matrix.Dims()
+	if c.store == nil || m.ANN.M < 1 || docs < m.ANN.MinVectors {
+		return false
+	}
+	state, err := c.store.State(indexKey(c))
+	if err != nil || state == nil {
+		return false
+	}
+	index, err := ann.Decode(bytes.NewReader(state))
+	if err != nil || index.Config() != m.ANN.Normalised() || index.Len() != docs {
+		return false
+	}
+	for i := 0; i < docs; i++ {
+		stored, current := index.Vector(i), m.normalised.RawRowView(i)
+		if len(stored) != len(current) {
+			return false
+		}
+		for j := range current {
+			if math.Abs(stored[j]-current[j]) > 1e-9 {
+				return false
+			}
+		}
+	}
+	m.index = index
+	return true
+}
+
+// storeIndex keeps the index built at training in the store of the corpus, so later trainings can restore it
+func (m *Model) storeIndex(c *Corpus) error {
+	if c.store == nil || m.index == nil {
+		return nil
+	}
+	buf := new(bytes.Buffer)
+	if err := m.index.Encode(buf); err != nil {
+		return err
+	}
+	return c.store.SetState(indexKey(c), buf.Bytes())
+}
+
+// withLexicalMatches adds documents with positive lexical scores to the candidates
+func withLexicalMatches(candidates []int, lexical []float64) []int {
+	seen := make(map[int]bool, len(candidates))
+	for _, i := range candidates {
+		seen[i] = true
+	}
+	for i, s := range lexical {
+		if s > 0 && !seen[i] {
+			candidates = append(candidates, i)
+		}
+	}
+	return candidates
+}
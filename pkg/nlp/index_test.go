@@ -0,0 +1,140 @@
+package nlp
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/stormcrows/qdox/pkg/ann"
+	"github.com/stormcrows/qdox/pkg/store"
+)
+
+func TestIndexedQuery(t *testing.T) {
+	c := NewCorpus()
+	if err := c.Load("../../books", regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+
+	exact := NewLSIModel()
+	exact.ANN = ann.Config{M: 4, EfConstruction: 10, EfSearch: 10, MinVectors: 5}
+	if err := exact.Train(&c); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+	if exact.Indexed() {
+		t.Errorf("expected corpus smaller than MinVectors not to be indexed")
+	}
+
+	// training releases contents of the corpus
+	c = NewCorpus()
+	if err := c.Load("../../books", regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	indexed := NewLSIModel()
+	indexed.ANN = ann.Config{M: 4, EfConstruction: 10, EfSearch: 10}
+	if err := indexed.Train(&c); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+	if !indexed.Indexed() {
+		t.Fatalf("expected corpus to be indexed")
+	}
+
+	for _, q := range []string{"wild weekend", "national park", "knight of valour"} {
+		want, got := exact.Query(q, 3, 0.1), indexed.Query(q, 3, 0.1)
+		if !reflect.DeepEqual(want.Matched, got.Matched) || !reflect.DeepEqual(want.Similarities, got.Similarities) {
+			t.Errorf("expected indexed query %q to match exact scan %v, got: %v", q, want, got)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := indexed.SaveIndex(buf); err != nil {
+		t.Fatalf("error saving index %s", err.Error())
+	}
+	if err := exact.LoadIndex(buf); err != nil {
+		t.Fatalf("error loading index %s", err.Error())
+	}
+	if qr := exact.Query("national park", 3, 0.1); !exact.Indexed() || len(qr.Matched) == 0 {
+		t.Errorf("expected loaded index to answer queries, got: %v", qr)
+	}
+}
+
+func TestLoadIndexMismatch(t *testing.T) {
+	c := NewCorpus()
+	if err := c.Load("../../books", regexp.MustCompile("Teton|Sword")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	m := NewLSIModelWith(2)
+	if err := m.Train(&c); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+
+	// index of other vectors than documents of the model
+	for _, vectors := range [][][]float64{{{1, 0}}, {{1, 0, 0}, {0, 1, 0}}} {
+		index := ann.NewHNSW(ann.Config{M: 4})
+		for _, v := range vectors {
+			index.Add(v)
+		}
+		buf := new(bytes.Buffer)
+		if err := index.Encode(buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.LoadIndex(buf); err == nil {
+			t.Errorf("expected index of %d vectors of %d dimensions to be rejected", len(vectors), len(vectors[0]))
+		}
+		if m.Indexed() {
+			t.Errorf("expected rejected index not to replace the model's")
+		}
+	}
+}
+
+func TestRestoreIndex(t *testing.T) {
+	dir := writeFiles(t, nil)
+	defer os.RemoveAll(dir)
+	s, err := store.Open(filepath.Join(dir, "qdox.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	train := func(config ann.Config) *Model {
+		c := NewCorpus()
+		c.UseStore(s)
+		if err := c.Load("../../books", regexp.MustCompile("\\.txt")); err != nil {
+			t.Fatalf("error reading folder %s", err.Error())
+		}
+		m := NewLSIModel()
+		m.ANN = config
+		if err := m.Train(&c); err != nil {
+			t.Fatalf("error training model %s", err.Error())
+		}
+		return m
+	}
+
+	config := ann.Config{M: 4, EfConstruction: 10, EfSearch: 10}
+	built := train(config)
+	if built.Stats().IndexRestored {
+		t.Errorf("expected index to be built by the first training")
+	}
+	restored := train(config)
+	if !restored.Stats().IndexRestored || !restored.Indexed() {
+		t.Errorf("expected index to be restored from the store by the second training")
+	}
+	want, got := built.Query("national park", 3, 0.1), restored.Query("national park", 3, 0.1)
+	if !reflect.DeepEqual(want.Matched, got.Matched) {
+		t.Errorf("expected restored index to answer as the built one %v, got: %v", want.Matched, got.Matched)
+	}
+
+	config.EfConstruction = 20
+	if train(config).Stats().IndexRestored {
+		t.Errorf("expected index built with other configuration not to be restored")
+	}
+
+	// the index links at least 2 neighbours, whatever the configuration asks for
+	config.M = 1
+	train(config)
+	if !train(config).Stats().IndexRestored {
+		t.Errorf("expected index built with raised number of neighbours to be restored")
+	}
+}
@@ -0,0 +1,131 @@
+package nlp
+
+import (
+	"math"
+	"sort"
+
+	"github.com/james-bowman/nlp"
+	"gonum.org/v1/gonum/mat"
+)
+
+const minKeyphraseCount = 2
+
+// Keyword is a characteristic term or phrase of the document along with its weight
+type Keyword struct {
+	Term   string
+	Weight float64
+}
+
+// nonZeroDoer is implemented by sparse matrices able to visit only their non zero elements
+type nonZeroDoer interface {
+	DoNonZero(fn func(i, j int, v float64))
+}
+
+// Keywords returns up to k highest TF-IDF weighted terms of the document retained at training
+func (m *Model) Keywords(doc int, k int) []Keyword {
+	return firstKeywords(m.keywords, doc, k)
+}
+
+// Keyphrases returns up to k highest weighted bigrams of the document, if extracted at training
+func (m *Model) Keyphrases(doc int, k int) []Keyword {
+	return firstKeywords(m.keyphrases, doc, k)
+}
+
+func firstKeywords(keywords [][]Keyword, doc int, k int) []Keyword {
+	if doc < 0 || doc >= len(keywords) {
+		return []Keyword{}
+	}
+	if len(keywords[doc]) > k {
+		return keywords[doc][:k]
+	}
+	return keywords[doc]
+}
+
+// extractKeywords retains top weighted terms of every document, and optionally bigrams of adjacent terms
+func (m *Model) extractKeywords(contents []string, counts mat.Matrix, weighted mat.Matrix) {
+	m.keywords, m.keyphrases = nil, nil
+	if m.MaxKeywords < 1 {
+		return
+	}
+
+	terms := m.Vocabulary()
+	_, docs := weighted.Dims()
+	candidates := make([][]Keyword, docs)
+	idf := make([]float64, len(terms))
+
+	visit := func(i, j int, v float64) {
+		if v == 0 {
+			return
+		}
+		candidates[j] = append(candidates[j], Keyword{terms[i], v})
+		if c := counts.At(i, j); c != 0 {
+			idf[i] = v / c
+		}
+	}
+
+	if sparse, ok := weighted.(nonZeroDoer); ok {
+		sparse.DoNonZero(visit)
+	} else {
+		rows, _ := weighted.Dims()
+		for i := 0; i < rows; i++ {
+			for j := 0; j < docs; j++ {
+				visit(i, j, weighted.At(i, j))
+			}
+		}
+	}
+
+	m.keywords = make([][]Keyword, docs)
+	for j, keywords := range candidates {
+		m.keywords[j] = topKeywords(keywords, m.MaxKeywords)
+	}
+
+	if !m.ExtractKeyphrases {
+		return
+	}
+
+	vectoriser, ok := m.Pipeline.Vectoriser.(*nlp.CountVectoriser)
+	if !ok {
+		return
+	}
+
+	m.keyphrases = make([][]Keyword, docs)
+	for j, content := range contents {
+		bigrams := make(map[string]int)
+		weights := make(map[string]float64)
+		previous := -1
+		for _, token := range vectoriser.Tokeniser.Tokenise(content) {
+			i, known := vectoriser.Vocabulary[token]
+			if !known || vectoriser.StopWords[token] {
+				previous = -1
+				continue
+			}
+			if previous >= 0 {
+				bigram := terms[previous] + " " + token
+				bigrams[bigram]++
+				weights[bigram] = math.Sqrt(idf[previous] * idf[i])
+			}
+			previous = i
+		}
+
+		keyphrases := make([]Keyword, 0)
+		for bigram, count := range bigrams {
+			if count >= minKeyphraseCount {
+				keyphrases = append(keyphrases, Keyword{bigram, float64(count) * weights[bigram]})
+			}
+		}
+		m.keyphrases[j] = topKeywords(keyphrases, m.MaxKeywords)
+	}
+}
+
+func topKeywords(keywords []Keyword, k int) []Keyword {
+	sort.SliceStable(keywords, func(a, b int) bool {
+		if keywords[a].Weight == keywords[b].Weight {
+			return keywords[a].Term < keywords[b].Term
+		}
+		return keywords[b].Weight < keywords[a].Weight
+	})
+	if len(keywords) > k {
+		keywords = keywords[:k]
+	}
+	return append(make([]Keyword, 0, len(keywords)), keywords...)
+}
@@ -0,0 +1,50 @@
+package nlp
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestKeywords(t *testing.T) {
+	c := NewCorpus()
+	if err := c.Load("../../books", regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	m := NewLSIModel()
+	m.ExtractKeyphrases = true
+	if err := m.Train(&c); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+
+	for i := 0; i < c.Len(); i++ {
+		keywords := m.Keywords(i, 5)
+		if len(keywords) != 5 {
+			t.Fatalf("expected 5 keywords for %q, got: %v", c.GetPath(i), keywords)
+		}
+		for j := 1; j < len(keywords); j++ {
+			if keywords[j].Weight > keywords[j-1].Weight {
+				t.Errorf("expected keywords ordered by weight, got: %v", keywords)
+			}
+		}
+
+		keyphrases := m.Keyphrases(i, 3)
+		if len(keyphrases) == 0 {
+			t.Errorf("expected keyphrases for %q", c.GetPath(i))
+		}
+		for _, keyphrase := range keyphrases {
+			if len(strings.Fields(keyphrase.Term)) != 2 {
+				t.Errorf("expected keyphrase to be a bigram, got: %q", keyphrase.Term)
+			}
+		}
+
+		if filepath.Base(c.GetPath(i)) == "Grand Teton National Park.txt" && keywords[0].Term != "teton" {
+			t.Errorf("expected teton to be the top keyword, got: %v", keywords)
+		}
+	}
+
+	if keywords := m.Keywords(c.Len(), 5); len(keywords) != 0 {
+		t.Errorf("expected no keywords for unknown document, got: %v", keywords)
+	}
+}
@@ -2,10 +2,14 @@ package nlp
 
 import (
 	"fmt"
+	"math"
 	"sort"
+	"sync/atomic"
+	"time"
 
 	"github.com/james-bowman/nlp"
-	"github.com/james-bowman/nlp/measures/pairwise"
+	"github.com/stormcrows/qdox/pkg/ann"
+	"github.com/stormcrows/qdox/pkg/suggest"
 	"gonum.org/v1/gonum/mat"
 )
 
@@ -14,68 +18,419 @@ type Model struct {
 	Pipeline *nlp.Pipeline
 	Matrix   mat.Matrix
 	Corpus   *Corpus
+	// Collapse folds matched duplicates resembling at least given similarity into versions of a single result, 0 disables it
+	Collapse float64
+	// MaxKeywords is the number of highest weighted terms retained per document at training
+	MaxKeywords int
+	// ExtractKeyphrases enables extraction of bigram keyphrases at training
+	ExtractKeyphrases bool
+	// Expansion is the number of terms closest in LSI term space added per query term, set before training, 0 disables it
+	Expansion int
+	// AutoCorrect reruns queries matching nothing with their suggested spelling
+	AutoCorrect bool
+	// Fusion combines LSI similarity of queries with BM25 scores, LSI only by default
+	Fusion Fusion
+	// ANN configures approximate nearest neighbour index of documents built at training, zero value disables it
+	ANN ann.Config
+	// Reduction configures fitting of the SVD stage at training, exact on all documents by default
+	Reduction   Reduction
+	stats       TrainingStats
+	energy      float64
+	frequencies []int
+	completions *suggest.Trie
+	keywords    [][]Keyword
+	keyphrases  [][]Keyword
+	synonyms    *synonyms
+	termVectors [][]float64
+	lexical     *lexicalIndex
+	index       *ann.HNSW
+	normalised  *mat.Dense
+	generation  uint64
 }
 
-// QueryResult contains indexes of matched documents along with their similarities
+// QueryResult contains indexes of matched documents along with their similarities,
+// indexes of other versions of each matched document when duplicates are collapsed,
+// terms the query was expanded with, and its suggested spelling, if results are Corrected to it.
+// Hybrid queries note Semantic LSI similarity and Lexical BM25 score of every matched document fused into its similarity
 type QueryResult struct {
 	Query        string
 	Matched      []int
 	Similarities []float64
+	Semantic     []float64
+	Lexical      []float64
+	Versions     [][]int
+	Expansions   []string
+	Suggestion   string
+	Corrected    bool
 	Err          error
+	// Paths of matched documents, set by point-in-time queries matching documents removed since, which have no index
+	Paths []string
+	// mean and deviation of similarities of all scored documents
+	mean      float64
+	deviation float64
 }
 
 var stopWords = []string{"a", "about", "above", "above", "across", "after", "afterwards", "again", "against", "all", "almost", "alone", "along", "already", "also", "although", "always", "am", "among", "amongst", "amoungst", "amount", "an", "and", "another", "any", "anyhow", "anyone", "anything", "anyway", "anywhere", "are", "around", "as", "at", "back", "be", "became", "because", "become", "becomes", "becoming", "been", "before", "beforehand", "behind", "being", "below", "beside", "besides", "between", "beyond", "bill", "both", "bottom", "but", "by", "call", "can", "cannot", "cant", "co", "con", "could", "couldnt", "cry", "de", "describe", "detail", "do", "done", "down", "due", "during", "each", "eg", "eight", "either", "eleven", "else", "elsewhere", "empty", "enough", "etc", "even", "ever", "every", "everyone", "everything", "everywhere", "except", "few", "fifteen", "fify", "fill", "find", "fire", "first", "five", "for", "former", "formerly", "forty", "found", "four", "from", "front", "full", "further", "get", "give", "go", "had", "has", "hasnt", "have", "he", "hence", "her", "here", "hereafter", "hereby", "herein", "hereupon", "hers", "herself", "him", "himself", "his", "how", "however", "hundred", "ie", "if", "in", "inc", "indeed", "interest", "into", "is", "it", "its", "itself", "keep", "last", "latter", "latterly", "least", "less", "ltd", "made", "many", "may", "me", "meanwhile", "might", "mill", "mine", "more", "moreover", "most", "mostly", "move", "much", "must", "my", "myself", "name", "namely", "neither", "never", "nevertheless", "next", "nine", "no", "nobody", "none", "noone", "nor", "not", "nothing", "now", "nowhere", "of", "off", "often", "on", "once", "one", "only", "onto", "or", "other", "others", "otherwise", "our", "ours", "ourselves", "out", "over", "own", "part", "per", "perhaps", "please", "put", "rather", "re", "same", "see", "seem", "seemed", "seeming", "seems", "serious", "several", "she", "should", "show", "side", "since", "sincere", "six", "sixty", "so", "some", "somehow", "someone", "something", "sometime", "sometimes", "somewhere", "still", "such", "system", "take", "ten", "than", "that", "the", "their", "them", "themselves", "then", "thence", "there", "thereafter", "thereby", "therefore", "therein", "thereupon", "these", "they", "thickv", "thin", "third", "this", "those", "though", "three", "through", "throughout", "thru", "thus", "to", "together", "too", "top", "toward", "towards", "twelve", "twenty", "two", "un", "under", "until", "up", "upon", "us", "very", "via", "was", "we", "well", "were", "what", "whatever", "when", "whence", "whenever", "where", "whereafter", "whereas", "whereby", "wherein", "whereupon", "wherever", "whether", "which", "while", "whither", "who", "whoever", "whole", "whom", "whose", "why", "will", "with", "within", "without", "would", "yet", "you", "your", "yours", "yourself", "yourselves"}
 
 // NewLSIModel initializes LSI pipeline
 func NewLSIModel() *Model {
+	return NewLSIModelWith(4, stopWords...)
+}
+
+// NewLSIModelWith initializes LSI pipeline reducing documents to k dimensions and ignoring given stop words
+func NewLSIModelWith(k int, stopWords ...string) *Model {
 	vectoriser := nlp.NewCountVectoriser(stopWords...)
 	transformer := nlp.NewTfidfTransformer()
-	reducer := nlp.NewTruncatedSVD(4)
+	reducer := nlp.NewTruncatedSVD(k)
 	pipeline := nlp.NewPipeline(vectoriser, transformer, reducer)
 
-	return &Model{pipeline, nil, nil}
+	return &Model{Pipeline: pipeline, MaxKeywords: 10}
+}
+
+// StopWords returns default english stop words ignored by the model
+func StopWords() []string {
+	return append([]string(nil), stopWords...)
+}
+
+// Vocabulary returns fitted terms ordered by their row index in the term space
+func (m *Model) Vocabulary() []string {
+	vectoriser, ok := m.Pipeline.Vectoriser.(*nlp.CountVectoriser)
+	if !ok {
+		return nil
+	}
+	terms := make([]string, len(vectoriser.Vocabulary))
+	for term, i := range vectoriser.Vocabulary {
+		terms[i] = term
+	}
+	return terms
+}
+
+// Terms fits only the vectoriser of the model to contents of the corpus, returning the number of terms it finds
+func (m *Model) Terms(c *Corpus) int {
+	m.Pipeline.Vectoriser.Fit(c.Contents()...)
+	return len(m.Vocabulary())
+}
+
+// components returns the term by concept matrix of the fitted SVD stage
+func (m *Model) components() mat.Matrix {
+	if len(m.Pipeline.Transformers) == 0 {
+		return nil
+	}
+	svd, ok := m.Pipeline.Transformers[len(m.Pipeline.Transformers)-1].(*nlp.TruncatedSVD)
+	if !ok || svd.Components == nil {
+		return nil
+	}
+	return svd.Components
+}
+
+// documentVectors returns L2 normalised LSI vectors of all trained documents
+func (m *Model) documentVectors() [][]float64 {
+	_, docs := m.Matrix.Dims()
+	vectors := make([][]float64, docs)
+	for i := 0; i < docs; i++ {
+		vectors[i] = normalise(mat.Col(nil, i, m.Matrix))
+	}
+	return vectors
+}
+
+// topTerms maps a vector in LSI space back onto the vocabulary and returns n highest weighted terms
+func (m *Model) topTerms(v []float64, n int) []string {
+	components, terms := m.components(), m.Vocabulary()
+	if components == nil || len(terms) == 0 {
+		return nil
+	}
+	weights := make([]float64, len(terms))
+	for i := range weights {
+		for j, x := range v {
+			weights[i] += components.At(i, j) * x
+		}
+	}
+	return topN(terms, weights, n)
+}
+
+func normalise(v []float64) []float64 {
+	norm := 0.0
+	for _, x := range v {
+		norm += x * x
+	}
+	if norm == 0 {
+		return v
+	}
+	norm = math.Sqrt(norm)
+	for i := range v {
+		v[i] /= norm
+	}
+	return v
+}
+
+// squaredNorm returns sum of squares of elements of the matrix, visiting only non zero ones of sparse matrices,
+// and 0 for an empty one
+func squaredNorm(a mat.Matrix) float64 {
+	rows, cols := a.Dims()
+	if rows == 0 || cols == 0 {
+		return 0
+	}
+	sum := 0.0
+	if sparse, ok := a.(nonZeroDoer); ok {
+		sparse.DoNonZero(func(i, j int, v float64) {
+			sum += v * v
+		})
+		return sum
+	}
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			sum += a.At(i, j) * a.At(i, j)
+		}
+	}
+	return sum
+}
+
+func dot(a, b []float64) float64 {
+	s := 0.0
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
+
+// topN returns up to n terms with highest positive weights
+func topN(terms []string, weights []float64, n int) []string {
+	idx := topIndexes(weights, n)
+	top := make([]string, len(idx))
+	for i, j := range idx {
+		top[i] = terms[j]
+	}
+	return top
+}
+
+// topIndexes returns indexes of up to n highest positive weights
+func topIndexes(weights []float64, n int) []int {
+	idx := make([]int, 0, len(weights))
+	for i, w := range weights {
+		if w > 0 {
+			idx = append(idx, i)
+		}
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return weights[idx[j]] < weights[idx[i]] })
+	if len(idx) > n {
+		idx = idx[:n]
+	}
+	return idx
 }
 
 // Train fits the model to the given corpus, resulting in lsi matrix
 func (m *Model) Train(c *Corpus) error {
-	lsi, err := m.Pipeline.FitTransform(c.Contents()...)
+	start, before := time.Now(), measure()
+	m.stats = TrainingStats{}
+
+	lsi, err := m.fit(c.Contents())
 	if err != nil {
 		return fmt.Errorf("Failed to process documents: %q", err.Error())
 	}
 	m.Matrix = lsi
+	if err := c.storeVectors(lsi); err != nil {
+		return fmt.Errorf("Failed to store document vectors: %q", err.Error())
+	}
+
+	indexing := time.Now()
+	m.normaliseDocuments()
+	if m.stats.IndexRestored = m.restoreIndex(c); !m.stats.IndexRestored {
+		m.buildIndex()
+		if err := m.storeIndex(c); err != nil {
+			return fmt.Errorf("Failed to store index: %q", err.Error())
+		}
+	}
+	m.computeTermVectors()
+	m.buildCompletions()
 	c.Release()
 	m.Corpus = c
+	m.stats.Index = time.Since(indexing)
+
+	after := measure()
+	m.stats.Total = time.Since(start)
+	m.stats.Allocated = after.TotalAlloc - before.TotalAlloc
+	m.stats.HeapInUse, m.stats.Sys = after.HeapInuse, after.Sys
+	m.changed()
 	return nil
 }
 
+// Generation counts changes of the model affecting query results, such as training it
+func (m *Model) Generation() uint64 {
+	return atomic.LoadUint64(&m.generation)
+}
+
+func (m *Model) changed() {
+	atomic.AddUint64(&m.generation, 1)
+}
+
+// fit runs pipeline stages one by one, noting total energy and keywords of the matrix entering the reduction stage
+func (m *Model) fit(contents []string) (mat.Matrix, error) {
+	start := time.Now()
+	counts, err := m.Pipeline.Vectoriser.FitTransform(contents...)
+	if err != nil {
+		return nil, err
+	}
+	m.countFrequencies(counts)
+	m.indexCounts(counts)
+	m.stats.Terms, m.stats.Documents = counts.Dims()
+	m.stats.Fitted = m.stats.Documents
+	m.stats.Vectorise = time.Since(start)
+
+	matrix := counts
+	for i, transformer := range m.Pipeline.Transformers {
+		start = time.Now()
+		if i == len(m.Pipeline.Transformers)-1 {
+			m.energy = squaredNorm(matrix)
+			m.extractKeywords(contents, counts, matrix)
+			if matrix, err = m.fitReduction(transformer, matrix); err != nil {
+				return nil, err
+			}
+			m.stats.Reduce = time.Since(start)
+			continue
+		}
+		if matrix, err = transformer.FitTransform(matrix); err != nil {
+			return nil, err
+		}
+		m.stats.Weigh += time.Since(start)
+	}
+	return matrix, nil
+}
+
 // Query returns document indexes matching given query
 func (m *Model) Query(q string, n int, threshold float64) QueryResult {
-	queryVector, err := m.Pipeline.Transform(q)
+	return m.QueryWith(q, n, threshold, m.Fusion)
+}
+
+// QueryWith returns document indexes matching given query, scoring them with given fusion instead of the model's one
+func (m *Model) QueryWith(q string, n int, threshold float64, fusion Fusion) QueryResult {
+	if err := fusion.Validate(); err != nil {
+		return QueryResult{Query: q, Err: err}
+	}
+
+	qr := m.query(q, n, threshold, fusion)
+	if qr.Err != nil {
+		return qr
+	}
+
+	// spelling is suggested for queries matching nothing, or if it matches better
+	suggestion := m.Suggest(q)
+	if suggestion == "" {
+		return qr
+	}
+	corrected := m.query(suggestion, n, threshold, fusion)
+	if corrected.Err != nil {
+		return qr
+	}
+	if len(qr.Matched) == 0 {
+		qr.Suggestion = suggestion
+		if m.AutoCorrect {
+			corrected.Query, corrected.Suggestion, corrected.Corrected = q, suggestion, true
+			return corrected
+		}
+	} else if len(corrected.Matched) > 0 && corrected.Similarities[0] > qr.Similarities[0] {
+		qr.Suggestion = suggestion
+	}
+
+	return qr
+}
+
+func (m *Model) query(q string, n int, threshold float64, fusion Fusion) QueryResult {
+	text, expansions := m.expand(q)
+	queryVector, err := m.Pipeline.Transform(text)
 	if err != nil {
-		return QueryResult{q, nil, nil, fmt.Errorf("Failed to process documents: %q", err.Error())}
+		return QueryResult{Query: q, Err: fmt.Errorf("Failed to process documents: %q", err.Error())}
 	}
 	_, docs := m.Matrix.Dims()
-	matched := make([]int, 0)
-	similarities := make([]float64, 0)
-	for i := 0; i < docs; i++ {
-		s := pairwise.CosineSimilarity(queryVector.(mat.ColViewer).ColView(0), m.Matrix.(mat.ColViewer).ColView(i))
-		if s >= threshold {
-			matched = append(matched, i)
-			similarities = append(similarities, s)
+	candidates := m.candidates(queryVector, n)
+	var lexical []float64
+	if fusion.Method != "" && m.lexical != nil {
+		counts, err := m.Pipeline.Vectoriser.Transform(text)
+		if err != nil {
+			return QueryResult{Query: q, Err: fmt.Errorf("Failed to process documents: %q", err.Error())}
+		}
+		lexical = m.bm25(counts)
+		if candidates != nil {
+			candidates = withLexicalMatches(candidates, lexical)
 		}
 	}
 
-	qr := QueryResult{q, matched, similarities, nil}
-	sort.Sort(&qr)
+	semantic := m.score(mat.Col(nil, 0, queryVector), candidates)
+	similarities := semantic
+	if lexical != nil {
+		similarities = fusion.fuse(semantic, lexical)
+	}
+
+	// collapsing needs all matches, as duplicates of better ones do not count towards n
+	limit := n
+	if m.Collapse > 0 {
+		limit = docs
+	}
+	matched, scores := topMatches(similarities, candidates, threshold, limit)
+	qr := QueryResult{Query: q, Matched: matched, Similarities: scores, Expansions: expansions}
+	qr.mean, qr.deviation = distribution(similarities, candidates)
+	if lexical != nil {
+		qr.Semantic, qr.Lexical = make([]float64, len(matched)), make([]float64, len(matched))
+		for i, doc := range matched {
+			qr.Semantic[i], qr.Lexical[i] = semantic[doc], lexical[doc]
+		}
+	}
+
+	if m.Collapse > 0 {
+		collapse(&qr, m.Corpus, m.Collapse)
+	}
 
 	if len(qr.Matched) > n {
 		qr.Matched = qr.Matched[0:n]
 		qr.Similarities = qr.Similarities[0:n]
+		if qr.Versions != nil {
+			qr.Versions = qr.Versions[0:n]
+		}
+		if qr.Lexical != nil {
+			qr.Semantic, qr.Lexical = qr.Semantic[0:n], qr.Lexical[0:n]
+		}
 	}
 
 	return qr
 }
 
+// collapse folds sorted results into versions of the best matching document of the corpus they duplicate,
+// along with versions they already have
+func collapse(qr *QueryResult, c *Corpus, similarity float64) {
+	matched := make([]int, 0, len(qr.Matched))
+	similarities := make([]float64, 0, len(qr.Similarities))
+	versions := make([][]int, 0, len(qr.Matched))
+	var semantic, lexical []float64
+
+next:
+	for i, doc := range qr.Matched {
+		for j, kept := range matched {
+			if c.Resemblance(kept, doc) >= similarity {
+				versions[j] = append(versions[j], doc)
+				if qr.Versions != nil {
+					versions[j] = append(versions[j], qr.Versions[i]...)
+				}
+				continue next
+			}
+		}
+		matched = append(matched, doc)
+		similarities = append(similarities, qr.Similarities[i])
+		if qr.Versions != nil {
+			versions = append(versions, qr.Versions[i])
+		} else {
+			versions = append(versions, make([]int, 0))
+		}
+		if qr.Lexical != nil {
+			semantic = append(semantic, qr.Semantic[i])
+			lexical = append(lexical, qr.Lexical[i])
+		}
+	}
+
+	qr.Matched, qr.Similarities, qr.Versions = matched, similarities, versions
+	if qr.Lexical != nil {
+		qr.Semantic, qr.Lexical = semantic, lexical
+	}
+}
+
 // Ordering of results
 
 func (qr *QueryResult) Len() int {
@@ -85,6 +440,13 @@ func (qr *QueryResult) Len() int {
 func (qr *QueryResult) Swap(i, j int) {
 	qr.Matched[i], qr.Matched[j] = qr.Matched[j], qr.Matched[i]
 	qr.Similarities[i], qr.Similarities[j] = qr.Similarities[j], qr.Similarities[i]
+	if qr.Versions != nil {
+		qr.Versions[i], qr.Versions[j] = qr.Versions[j], qr.Versions[i]
+	}
+	if qr.Lexical != nil {
+		qr.Semantic[i], qr.Semantic[j] = qr.Semantic[j], qr.Semantic[i]
+		qr.Lexical[i], qr.Lexical[j] = qr.Lexical[j], qr.Lexical[i]
+	}
 }
 
 func (qr *QueryResult) Less(i, j int) bool {
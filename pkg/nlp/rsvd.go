@@ -0,0 +1,170 @@
+package nlp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// randomisedSVD approximates k left singular vectors of the term by document matrix restricted to given columns,
+// or all of them if columns are nil, from its projection onto k+oversampling random directions,
+// refined by given number of power iterations. Sparse matrices are only visited by their non zero elements
+func randomisedSVD(a mat.Matrix, columns []int, k, oversampling, iterations int, rng *rand.Rand) (*mat.Dense, error) {
+	terms, docs := a.Dims()
+
+	// position of documents among decomposed columns, -1 for the rest
+	position := make([]int, docs)
+	n := docs
+	for j := range position {
+		position[j] = j
+	}
+	if columns != nil {
+		for j := range position {
+			position[j] = -1
+		}
+		for c, j := range columns {
+			position[j] = c
+		}
+		n = len(columns)
+	}
+
+	l := k + oversampling
+	if l > terms {
+		l = terms
+	}
+	if l > n {
+		l = n
+	}
+	if l < 1 {
+		return nil, fmt.Errorf("nothing to decompose in %dx%d matrix", terms, n)
+	}
+	if k > l {
+		k = l
+	}
+
+	omega := mat.NewDense(n, l, nil)
+	for i := 0; i < n; i++ {
+		row := omega.RawRowView(i)
+		for j := range row {
+			row[j] = rng.NormFloat64()
+		}
+	}
+
+	q := multiplyColumns(a, position, omega, terms)
+	orthonormalise(q)
+	for i := 0; i < iterations; i++ {
+		z := multiplyRows(a, position, q, n)
+		orthonormalise(z)
+		q = multiplyColumns(a, position, z, terms)
+		orthonormalise(q)
+	}
+
+	// left singular vectors of the small matrix Qᵀ A are right singular vectors of its transpose Aᵀ Q
+	var svd mat.SVD
+	if !svd.Factorize(multiplyRows(a, position, q, n), mat.SVDThin) {
+		return nil, fmt.Errorf("failed to decompose projection of the matrix")
+	}
+	var v mat.Dense
+	svd.VTo(&v)
+
+	var components mat.Dense
+	components.Mul(q, v.Slice(0, l, 0, k))
+	return &components, nil
+}
+
+// multiplyColumns returns the product of the matrix restricted to positioned columns and b, with rows of b in their positions
+func multiplyColumns(a mat.Matrix, position []int, b *mat.Dense, rows int) *mat.Dense {
+	_, cols := b.Dims()
+	product := mat.NewDense(rows, cols, nil)
+	eachNonZero(a, func(i, j int, v float64) {
+		if position[j] < 0 {
+			return
+		}
+		axpy(product.RawRowView(i), v, b.RawRowView(position[j]))
+	})
+	return product
+}
+
+// multiplyRows returns the product of the transpose of the matrix restricted to positioned columns and b
+func multiplyRows(a mat.Matrix, position []int, b *mat.Dense, rows int) *mat.Dense {
+	_, cols := b.Dims()
+	product := mat.NewDense(rows, cols, nil)
+	eachNonZero(a, func(i, j int, v float64) {
+		if position[j] < 0 {
+			return
+		}
+		axpy(product.RawRowView(position[j]), v, b.RawRowView(i))
+	})
+	return product
+}
+
+// project returns documents of the matrix projected onto the components, the same as Componentsᵀ A
+func project(components *mat.Dense, a mat.Matrix) *mat.Dense {
+	_, k := components.Dims()
+	_, docs := a.Dims()
+	projected := mat.NewDense(k, docs, nil)
+	eachNonZero(a, func(i, j int, v float64) {
+		for c, x := range components.RawRowView(i) {
+			projected.RawRowView(c)[j] += v * x
+		}
+	})
+	return projected
+}
+
+// orthonormalise turns columns of the matrix into an orthonormal basis of their span with modified Gram-Schmidt,
+// zeroing columns dependent on previous ones
+func orthonormalise(d *mat.Dense) {
+	rows, cols := d.Dims()
+	for j := 0; j < cols; j++ {
+		for p := 0; p < j; p++ {
+			projection := 0.0
+			for i := 0; i < rows; i++ {
+				row := d.RawRowView(i)
+				projection += row[j] * row[p]
+			}
+			for i := 0; i < rows; i++ {
+				row := d.RawRowView(i)
+				row[j] -= projection * row[p]
+			}
+		}
+
+		norm := 0.0
+		for i := 0; i < rows; i++ {
+			x := d.At(i, j)
+			norm += x * x
+		}
+		norm = math.Sqrt(norm)
+		for i := 0; i < rows; i++ {
+			if norm < 1e-10 {
+				d.Set(i, j, 0)
+			} else {
+				d.Set(i, j, d.At(i, j)/norm)
+			}
+		}
+	}
+}
+
+// eachNonZero visits non zero elements of the matrix, without visiting zeros of sparse matrices at all
+func eachNonZero(a mat.Matrix, fn func(i, j int, v float64)) {
+	if sparse, ok := a.(nonZeroDoer); ok {
+		sparse.DoNonZero(fn)
+		return
+	}
+	rows, cols := a.Dims()
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			if v := a.At(i, j); v != 0 {
+				fn(i, j, v)
+			}
+		}
+	}
+}
+
+// axpy adds x multiplied by a to y
+func axpy(y []float64, a float64, x []float64) {
+	for i, v := range x {
+		y[i] += a * v
+	}
+}
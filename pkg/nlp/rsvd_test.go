@@ -0,0 +1,115 @@
+package nlp
+
+import (
+	"math"
+	"math/rand"
+	"regexp"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestRandomisedSVD(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	terms, docs, rank := 40, 30, 3
+
+	// low rank matrix with a little noise
+	a := mat.NewDense(terms, docs, nil)
+	for r := 0; r < rank; r++ {
+		u, v := randomVector(rng, terms), randomVector(rng, docs)
+		for i := range u {
+			for j := range v {
+				a.Set(i, j, a.At(i, j)+float64(rank-r)*10*u[i]*v[j])
+			}
+		}
+	}
+	for i := 0; i < terms; i++ {
+		for j := 0; j < docs; j++ {
+			a.Set(i, j, a.At(i, j)+rng.NormFloat64()*0.01)
+		}
+	}
+
+	var svd mat.SVD
+	svd.Factorize(a, mat.SVDThin)
+	var exact mat.Dense
+	svd.UTo(&exact)
+
+	components, err := randomisedSVD(a, nil, rank, 5, 2, rng)
+	if err != nil {
+		t.Fatalf("error decomposing matrix %s", err.Error())
+	}
+	if r, c := components.Dims(); r != terms || c != rank {
+		t.Fatalf("expected %dx%d components, got: %dx%d", terms, rank, r, c)
+	}
+	for k := 0; k < rank; k++ {
+		if similarity := math.Abs(dot(mat.Col(nil, k, &exact), mat.Col(nil, k, components))); similarity < 0.99 {
+			t.Errorf("expected component %d to match the exact one, got similarity: %f", k, similarity)
+		}
+	}
+
+	if _, err := randomisedSVD(a, []int{}, rank, 5, 2, rng); err == nil {
+		t.Errorf("expected error decomposing no documents")
+	}
+}
+
+func TestRandomisedTraining(t *testing.T) {
+	for _, reduction := range []Reduction{DefaultRandomised(), {Sample: 3, Seed: 1}, {Randomised: true, Sample: 3, Seed: 1}} {
+		c := NewCorpus()
+		if err := c.Load("../../books", regexp.MustCompile("\\.txt")); err != nil {
+			t.Fatalf("error reading folder %s", err.Error())
+		}
+		m := NewLSIModel()
+		m.Reduction = reduction
+		if err := m.Train(&c); err != nil {
+			t.Fatalf("error training model with %+v: %s", reduction, err.Error())
+		}
+
+		stats := m.Stats()
+		wantFitted := stats.Documents
+		if reduction.Sample > 0 {
+			wantFitted = reduction.Sample
+		}
+		if stats.Documents != 4 || stats.Fitted != wantFitted || stats.Terms == 0 || stats.Total <= 0 || stats.Allocated == 0 {
+			t.Errorf("expected stats of training with %+v, got: %+v", reduction, stats)
+		}
+
+		// folded in documents are scored along with fitted ones
+		qr := m.Query("national park", 4, -1)
+		if len(qr.Matched) != 4 || c.GetPath(qr.Matched[0]) != "../../books/Grand Teton National Park.txt" {
+			t.Errorf("expected the park to match best with %+v, got: %v", reduction, qr)
+		}
+	}
+}
+
+// sparseOnly is a sparse matrix whose elements can only be visited if they are non zero
+type sparseOnly struct {
+	*mat.Dense
+}
+
+func (s sparseOnly) At(i, j int) float64 {
+	panic("elements should not be read one by one")
+}
+
+func (s sparseOnly) DoNonZero(fn func(i, j int, v float64)) {
+	rows, cols := s.Dense.Dims()
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			if v := s.Dense.At(i, j); v != 0 {
+				fn(i, j, v)
+			}
+		}
+	}
+}
+
+func TestSquaredNorm(t *testing.T) {
+	a := mat.NewDense(2, 3, []float64{1, 0, 2, 0, 0, 3})
+	if got := squaredNorm(a); got != 14 {
+		t.Errorf("expected 14, got: %g", got)
+	}
+	if got := squaredNorm(sparseOnly{a}); got != 14 {
+		t.Errorf("expected 14 from non zero elements, got: %g", got)
+	}
+	if got := squaredNorm(&mat.Dense{}); got != 0 {
+		t.Errorf("expected 0 for empty matrix, got: %g", got)
+	}
+}
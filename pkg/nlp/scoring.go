@@ -0,0 +1,133 @@
+package nlp
+
+import (
+	"container/heap"
+	"runtime"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// minParallelDocuments is the number of documents below which scoring them in parallel does not pay off
+const minParallelDocuments = 4096
+
+// normaliseDocuments stores L2 normalised LSI vectors of documents as rows of a matrix,
+// so cosine similarities of a query to all of them are a single matrix-vector product
+func (m *Model) normaliseDocuments() {
+	m.normalised = nil
+	dims, docs := m.Matrix.Dims()
+	if dims == 0 || docs == 0 {
+		return
+	}
+
+	normalised := mat.NewDense(docs, dims, nil)
+	for i := 0; i < docs; i++ {
+		normalised.SetRow(i, normalise(mat.Col(nil, i, m.Matrix)))
+	}
+	m.normalised = normalised
+}
+
+// score returns cosine similarities of the query vector to candidate documents, or all of them if candidates are nil,
+// leaving similarities of other documents 0
+func (m *Model) score(q []float64, candidates []int) []float64 {
+	_, docs := m.Matrix.Dims()
+	scores := make([]float64, docs)
+	if m.normalised == nil {
+		return scores
+	}
+	q = normalise(q)
+
+	if candidates != nil {
+		for _, i := range candidates {
+			scores[i] = dot(m.normalised.RawRowView(i), q)
+		}
+		return scores
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if docs < minParallelDocuments || workers < 2 {
+		workers = 1
+	}
+	chunk := (docs + workers - 1) / workers
+	v := mat.NewVecDense(len(q), q)
+
+	var wg sync.WaitGroup
+	for start := 0; start < docs; start += chunk {
+		end := start + chunk
+		if end > docs {
+			end = docs
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			dst := mat.NewVecDense(end-start, scores[start:end])
+			dst.MulVec(m.normalised.Slice(start, end, 0, len(q)), v)
+		}(start, end)
+	}
+	wg.Wait()
+
+	return scores
+}
+
+// topMatches returns up to limit candidate documents, or any of them if candidates are nil,
+// with similarities of at least threshold, best first
+func topMatches(similarities []float64, candidates []int, threshold float64, limit int) ([]int, []float64) {
+	h := &matchHeap{similarities: similarities}
+	consider := func(i int) {
+		if similarities[i] < threshold {
+			return
+		}
+		if h.Len() < limit {
+			heap.Push(h, i)
+		} else if limit > 0 && h.worse(h.docs[0], i) {
+			h.docs[0] = i
+			heap.Fix(h, 0)
+		}
+	}
+
+	if candidates == nil {
+		for i := range similarities {
+			consider(i)
+		}
+	} else {
+		for _, i := range candidates {
+			consider(i)
+		}
+	}
+
+	matched := make([]int, h.Len())
+	scores := make([]float64, h.Len())
+	for i := len(matched) - 1; i >= 0; i-- {
+		matched[i] = heap.Pop(h).(int)
+		scores[i] = similarities[matched[i]]
+	}
+	return matched, scores
+}
+
+// matchHeap keeps the worst of documents on top, so it is replaced first by better ones
+type matchHeap struct {
+	docs         []int
+	similarities []float64
+}
+
+// worse tells whether document a ranks below document b, ranking lower indexes first on ties
+func (h *matchHeap) worse(a, b int) bool {
+	if h.similarities[a] == h.similarities[b] {
+		return a > b
+	}
+	return h.similarities[a] < h.similarities[b]
+}
+
+func (h *matchHeap) Len() int { return len(h.docs) }
+
+func (h *matchHeap) Less(i, j int) bool { return h.worse(h.docs[i], h.docs[j]) }
+
+func (h *matchHeap) Swap(i, j int) { h.docs[i], h.docs[j] = h.docs[j], h.docs[i] }
+
+func (h *matchHeap) Push(x interface{}) { h.docs = append(h.docs, x.(int)) }
+
+func (h *matchHeap) Pop() interface{} {
+	last := h.docs[len(h.docs)-1]
+	h.docs = h.docs[:len(h.docs)-1]
+	return last
+}
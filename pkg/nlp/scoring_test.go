@@ -0,0 +1,107 @@
+package nlp
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/james-bowman/nlp/measures/pairwise"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestTopMatches(t *testing.T) {
+	similarities := []float64{0.2, 0.9, 0.5, 0.9, 0.1, 0.7}
+
+	matched, scores := topMatches(similarities, nil, 0.3, 3)
+	if want := []int{1, 3, 5}; !reflect.DeepEqual(want, matched) {
+		t.Errorf("expected best matches %v, got: %v", want, matched)
+	}
+	if want := []float64{0.9, 0.9, 0.7}; !reflect.DeepEqual(want, scores) {
+		t.Errorf("expected similarities %v, got: %v", want, scores)
+	}
+
+	if matched, _ := topMatches(similarities, nil, 0.3, 10); !reflect.DeepEqual([]int{1, 3, 5, 2}, matched) {
+		t.Errorf("expected all matches above threshold, got: %v", matched)
+	}
+	if matched, _ := topMatches(similarities, []int{0, 2, 4}, 0.0, 2); !reflect.DeepEqual([]int{2, 0}, matched) {
+		t.Errorf("expected only candidates to match, got: %v", matched)
+	}
+	if matched, _ := topMatches(similarities, nil, 0.0, 0); len(matched) != 0 {
+		t.Errorf("expected no matches, got: %v", matched)
+	}
+}
+
+func TestScore(t *testing.T) {
+	m := randomModel(5000, 16)
+	q := randomVector(rand.New(rand.NewSource(2)), 16)
+
+	scores := m.score(append([]float64(nil), q...), nil)
+	for i := range scores {
+		want := pairwise.CosineSimilarity(mat.NewVecDense(len(q), q), m.Matrix.(mat.ColViewer).ColView(i))
+		if math.Abs(want-scores[i]) > 1e-9 {
+			t.Fatalf("expected similarity %f of document %d, got: %f", want, i, scores[i])
+		}
+	}
+
+	candidates := m.score(append([]float64(nil), q...), []int{3, 7})
+	if candidates[3] != scores[3] || candidates[7] != scores[7] || candidates[0] != 0 {
+		t.Errorf("expected only candidates to be scored")
+	}
+}
+
+func randomVector(rng *rand.Rand, dims int) []float64 {
+	v := make([]float64, dims)
+	for i := range v {
+		v[i] = rng.NormFloat64()
+	}
+	return v
+}
+
+// randomModel returns a model with random LSI matrix of given number of documents
+func randomModel(docs, dims int) *Model {
+	rng := rand.New(rand.NewSource(1))
+	matrix := mat.NewDense(dims, docs, nil)
+	for j := 0; j < docs; j++ {
+		matrix.SetCol(j, randomVector(rng, dims))
+	}
+	m := NewLSIModel()
+	m.Matrix = matrix
+	m.normaliseDocuments()
+	return m
+}
+
+// scanQuery scores documents one by one and sorts all matches, the way queries did before pre-normalising documents
+func scanQuery(m *Model, q []float64, n int) QueryResult {
+	_, docs := m.Matrix.Dims()
+	v := mat.NewVecDense(len(q), q)
+	qr := QueryResult{Matched: make([]int, 0), Similarities: make([]float64, 0)}
+	for i := 0; i < docs; i++ {
+		if s := pairwise.CosineSimilarity(v, m.Matrix.(mat.ColViewer).ColView(i)); s >= 0 {
+			qr.Matched = append(qr.Matched, i)
+			qr.Similarities = append(qr.Similarities, s)
+		}
+	}
+	sort.Sort(&qr)
+	qr.Matched, qr.Similarities = qr.Matched[:n], qr.Similarities[:n]
+	return qr
+}
+
+func BenchmarkScanQuery(b *testing.B) {
+	m := randomModel(100000, 100)
+	q := randomVector(rand.New(rand.NewSource(2)), 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scanQuery(m, q, 10)
+	}
+}
+
+func BenchmarkScoreQuery(b *testing.B) {
+	m := randomModel(100000, 100)
+	q := randomVector(rand.New(rand.NewSource(2)), 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		topMatches(m.score(append([]float64(nil), q...), nil), nil, 0, 10)
+	}
+}
@@ -0,0 +1,286 @@
+package nlp
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Shard is a part of the corpus trained into its own model, knowing indexes of its documents in the whole corpus
+type Shard struct {
+	Name      string
+	Corpus    Corpus
+	Model     *Model
+	documents []int
+}
+
+// Sharded fans queries out to models trained on shards of the corpus in parallel, merging their results.
+// LSI spaces of shards differ, so similarities of their results are normalised by the distribution
+// of similarities of all documents in the shard before they are ranked together
+type Sharded struct {
+	Corpus *Corpus
+	Shards []*Shard
+	// shard and index within the shard of every document of the corpus
+	shardOf []int
+	localOf []int
+}
+
+// BySubfolder keys documents by the top level subfolder of root they are in, or "." for files directly in root
+func BySubfolder(root string) func(path string) string {
+	return func(path string) string {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return "."
+		}
+		parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+		if len(parts) < 2 {
+			return "."
+		}
+		return parts[0]
+	}
+}
+
+// ByHash keys documents by hash of their path into n shards
+func ByHash(n int) func(path string) string {
+	return func(path string) string {
+		h := fnv.New32a()
+		h.Write([]byte(path))
+		return fmt.Sprintf("%d", h.Sum32()%uint32(n))
+	}
+}
+
+// NewSharded splits documents of the corpus into shards named after keys of documents' paths,
+// with models of shards returned by newModel
+func NewSharded(c *Corpus, key func(path string) string, newModel func() *Model) *Sharded {
+	s := &Sharded{Corpus: c, shardOf: make([]int, c.Len()), localOf: make([]int, c.Len())}
+	shards := make(map[string]*Shard)
+	for i, doc := range c.documents {
+		name := key(doc.path)
+		shard, ok := shards[name]
+		if !ok {
+			shard = &Shard{Name: name, Corpus: Corpus{documents: make([]Document, 0), filter: c.filter, store: c.store, shard: name}, Model: newModel()}
+			shards[name] = shard
+			s.Shards = append(s.Shards, shard)
+		}
+		shard.Corpus.documents = append(shard.Corpus.documents, doc)
+		shard.documents = append(shard.documents, i)
+	}
+
+	sort.Slice(s.Shards, func(i, j int) bool { return s.Shards[i].Name < s.Shards[j].Name })
+	for i, shard := range s.Shards {
+		for j, doc := range shard.documents {
+			s.shardOf[doc], s.localOf[doc] = i, j
+		}
+	}
+	return s
+}
+
+// Train trains models of all shards in parallel, releasing contents of the corpus afterwards
+func (s *Sharded) Train() error {
+	errs := make([]error, len(s.Shards))
+	var wg sync.WaitGroup
+	for i, shard := range s.Shards {
+		wg.Add(1)
+		go func(i int, shard *Shard) {
+			defer wg.Done()
+			if err := shard.Model.Train(&shard.Corpus); err != nil {
+				errs[i] = fmt.Errorf("shard %s: %s", shard.Name, err)
+			}
+		}(i, shard)
+	}
+	wg.Wait()
+
+	s.Corpus.Release()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Query returns indexes of documents of the corpus matching given query, ranked by normalised similarities
+func (s *Sharded) Query(q string, n int, threshold float64) QueryResult {
+	if len(s.Shards) == 0 {
+		return QueryResult{Query: q, Matched: []int{}, Similarities: []float64{}}
+	}
+	return s.QueryWith(q, n, threshold, s.Shards[0].Model.Fusion)
+}
+
+// QueryWith queries every shard for its top n results with given fusion, merging the top n of them.
+// Threshold applies to similarities within shards, the merged ones are normalised
+func (s *Sharded) QueryWith(q string, n int, threshold float64, fusion Fusion) QueryResult {
+	results := make([]QueryResult, len(s.Shards))
+	var wg sync.WaitGroup
+	for i, shard := range s.Shards {
+		wg.Add(1)
+		go func(i int, shard *Shard) {
+			defer wg.Done()
+			results[i] = shard.Model.QueryWith(q, n, threshold, fusion)
+		}(i, shard)
+	}
+	wg.Wait()
+
+	merged := QueryResult{Query: q, Matched: make([]int, 0), Similarities: make([]float64, 0)}
+	expanded := make(map[string]bool)
+	suggestions := make(map[string]int)
+	for i, result := range results {
+		if result.Err != nil {
+			return QueryResult{Query: q, Err: fmt.Errorf("shard %s: %s", s.Shards[i].Name, result.Err)}
+		}
+		for _, term := range result.Expansions {
+			if !expanded[term] {
+				expanded[term] = true
+				merged.Expansions = append(merged.Expansions, term)
+			}
+		}
+		if result.Suggestion != "" {
+			suggestions[result.Suggestion] += len(s.Shards[i].documents)
+		}
+
+		shard := s.Shards[i]
+		for j, doc := range result.Matched {
+			merged.Matched = append(merged.Matched, shard.documents[doc])
+			merged.Similarities = append(merged.Similarities, normalCDF(result.Similarities[j], result.mean, result.deviation))
+			if result.Lexical != nil {
+				merged.Semantic = append(merged.Semantic, result.Semantic[j])
+				merged.Lexical = append(merged.Lexical, result.Lexical[j])
+			}
+			if result.Versions != nil {
+				versions := make([]int, len(result.Versions[j]))
+				for k, version := range result.Versions[j] {
+					versions[k] = shard.documents[version]
+				}
+				merged.Versions = append(merged.Versions, versions)
+			}
+		}
+	}
+
+	// suggestion of shards holding most documents wins
+	for suggestion, weight := range suggestions {
+		if weight > suggestions[merged.Suggestion] || (weight == suggestions[merged.Suggestion] && suggestion < merged.Suggestion) {
+			merged.Suggestion = suggestion
+		}
+	}
+	for _, result := range results {
+		merged.Corrected = merged.Corrected || result.Corrected
+	}
+
+	if len(merged.Versions) != len(merged.Matched) {
+		merged.Versions = nil
+	}
+	if len(merged.Lexical) != len(merged.Matched) {
+		merged.Semantic, merged.Lexical = nil, nil
+	}
+	sort.Stable(&merged)
+
+	// duplicates in different shards are folded together too
+	if len(s.Shards) > 0 && s.Shards[0].Model.Collapse > 0 {
+		collapse(&merged, s.Corpus, s.Shards[0].Model.Collapse)
+	}
+
+	if len(merged.Matched) > n {
+		merged.Matched, merged.Similarities = merged.Matched[:n], merged.Similarities[:n]
+		if merged.Versions != nil {
+			merged.Versions = merged.Versions[:n]
+		}
+		if merged.Lexical != nil {
+			merged.Semantic, merged.Lexical = merged.Semantic[:n], merged.Lexical[:n]
+		}
+	}
+	return merged
+}
+
+// Snippet returns the passage of the document of the corpus best matching the query
+func (s *Sharded) Snippet(doc int, q string, words int) (string, error) {
+	return s.Shards[s.shardOf[doc]].Model.Snippet(s.localOf[doc], q, words)
+}
+
+// Explain breaks down similarity of the query and the document of the corpus within its shard
+func (s *Sharded) Explain(q string, doc int) (Explanation, error) {
+	if doc < 0 || doc >= len(s.shardOf) {
+		return Explanation{}, fmt.Errorf("document %d out of range [0, %d)", doc, len(s.shardOf))
+	}
+	e, err := s.Shards[s.shardOf[doc]].Model.Explain(q, s.localOf[doc])
+	e.Document = doc
+	return e, err
+}
+
+// Complete returns up to n vocabulary terms of all shards starting with the prefix,
+// ranked by their document frequencies summed across shards
+func (s *Sharded) Complete(prefix string, n int) []string {
+	weights := make(map[string]int)
+	for _, shard := range s.Shards {
+		if shard.Model.completions == nil {
+			continue
+		}
+		for _, c := range shard.Model.completions.Complete(prefix, n) {
+			weights[c.Text] += c.Weight
+		}
+	}
+
+	terms := make([]string, 0, len(weights))
+	for term := range weights {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if weights[terms[i]] != weights[terms[j]] {
+			return weights[terms[i]] > weights[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	if len(terms) > n {
+		terms = terms[:n]
+	}
+	return terms
+}
+
+// Generation counts changes of models of all shards
+func (s *Sharded) Generation() uint64 {
+	var generation uint64
+	for _, shard := range s.Shards {
+		generation += shard.Model.Generation()
+	}
+	return generation
+}
+
+// distribution returns mean and standard deviation of similarities of candidate documents, or all of them if candidates are nil
+func distribution(similarities []float64, candidates []int) (float64, float64) {
+	count, sum, squares := 0, 0.0, 0.0
+	add := func(s float64) {
+		count++
+		sum += s
+		squares += s * s
+	}
+	if candidates == nil {
+		for _, s := range similarities {
+			add(s)
+		}
+	} else {
+		for _, i := range candidates {
+			add(similarities[i])
+		}
+	}
+	if count == 0 {
+		return 0, 0
+	}
+
+	mean := sum / float64(count)
+	return mean, math.Sqrt(math.Max(0, squares/float64(count)-mean*mean))
+}
+
+// normalCDF maps the similarity onto probability of a lower one in normal distribution of given mean and deviation.
+// Without deviation all similarities are alike, so they map onto the middle of the scale, unless they are exact matches
+func normalCDF(similarity, mean, deviation float64) float64 {
+	if deviation < 1e-9 {
+		if similarity > 1-1e-9 {
+			return 1
+		}
+		return 0.5
+	}
+	return 0.5 * (1 + math.Erf((similarity-mean)/(deviation*math.Sqrt2)))
+}
@@ -0,0 +1,103 @@
+package nlp
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestShardedQuery(t *testing.T) {
+	folder := writeFiles(t, map[string]string{
+		"parks/yellowstone.txt": "yellowstone national park geysers hot springs bison wolves",
+		"parks/teton.txt":       "grand teton national park mountains lakes climbing trails",
+		"parks/yosemite.txt":    "yosemite valley granite cliffs waterfalls climbing sequoias",
+		"parks/acadia.txt":      "acadia coast lighthouse granite mountains trails ocean",
+		"parks/zion.txt":        "zion canyon sandstone cliffs river hiking trails",
+		"food/bread.txt":        "bread flour yeast dough oven baking crust",
+		"food/sausage.txt":      "sausage pork meat spices casing smoking grill",
+		"food/cheese.txt":       "cheese milk cultures aging cellar rind wheels",
+		"food/pasta.txt":        "pasta flour eggs dough boiling sauce tomatoes",
+		"food/soup.txt":         "soup broth vegetables boiling pot spices bread",
+	})
+	defer os.RemoveAll(folder)
+
+	c := NewCorpus()
+	if err := c.Load(folder, regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	s := NewSharded(&c, BySubfolder(folder), func() *Model { return NewLSIModelWith(3, StopWords()...) })
+	if len(s.Shards) != 2 || s.Shards[0].Name != "food" || s.Shards[0].Corpus.Len() != 5 {
+		t.Fatalf("expected 2 shards of 5 documents, got: %v", s.Shards)
+	}
+	if err := s.Train(); err != nil {
+		t.Fatalf("error training shards %s", err.Error())
+	}
+
+	qr := s.Query("yellowstone geysers", 3, 0.0)
+	if qr.Err != nil {
+		t.Fatalf("error querying shards %s", qr.Err.Error())
+	}
+	if len(qr.Matched) == 0 || filepath.Base(c.GetPath(qr.Matched[0])) != "yellowstone.txt" {
+		t.Errorf("expected yellowstone to rank first, got: %v", qr.Matched)
+	}
+	for i, similarity := range qr.Similarities {
+		if similarity < 0 || similarity > 1 || (i > 0 && similarity > qr.Similarities[i-1]) {
+			t.Errorf("expected normalised similarities in descending order, got: %v", qr.Similarities)
+		}
+	}
+	if len(qr.Matched) != 3 {
+		t.Errorf("expected merged results cut to n, got: %v", qr.Matched)
+	}
+
+	if snippet, err := s.Snippet(qr.Matched[0], "geysers", 3); err != nil || !strings.Contains(snippet, "geysers") {
+		t.Errorf("expected snippet of the document from its shard, got: %q %v", snippet, err)
+	}
+	if e, err := s.Explain("geysers", qr.Matched[0]); err != nil || e.Document != qr.Matched[0] {
+		t.Errorf("expected explanation of the document from its shard, got: %v %v", e, err)
+	}
+	if terms := s.Complete("gr", 2); !reflect.DeepEqual(terms, []string{"granite", "grand"}) {
+		t.Errorf("expected terms of both shards ranked by their summed frequencies, got: %v", terms)
+	}
+}
+
+func TestShardKeys(t *testing.T) {
+	bySubfolder := BySubfolder("docs")
+	if key := bySubfolder("docs/reports/2019/q1.txt"); key != "reports" {
+		t.Errorf("expected top level subfolder, got: %s", key)
+	}
+	if key := bySubfolder("docs/readme.txt"); key != "." {
+		t.Errorf("expected root shard, got: %s", key)
+	}
+
+	byHash := ByHash(3)
+	if byHash("docs/a.txt") != byHash("docs/a.txt") {
+		t.Errorf("expected the same key of the same path")
+	}
+	for _, path := range []string{"a", "b", "c", "d", "e"} {
+		if key := byHash(path); key != "0" && key != "1" && key != "2" {
+			t.Errorf("expected one of 3 keys, got: %s", key)
+		}
+	}
+}
+
+func TestNormalCDF(t *testing.T) {
+	for _, c := range []struct {
+		similarity, mean, deviation, want float64
+	}{
+		{0.5, 0.5, 0.1, 0.5},
+		{0.3, 0.3, 0, 0.5},
+		{0.9, 0.9, 0, 0.5},
+		{1, 1, 0, 1},
+	} {
+		if got := normalCDF(c.similarity, c.mean, c.deviation); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("expected %g for similarity %g of mean %g and deviation %g, got: %g", c.want, c.similarity, c.mean, c.deviation, got)
+		}
+	}
+	if normalCDF(0.8, 0.5, 0.1) <= normalCDF(0.6, 0.5, 0.1) {
+		t.Errorf("expected higher similarities to map higher")
+	}
+}
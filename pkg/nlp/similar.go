@@ -0,0 +1,41 @@
+package nlp
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Similar returns indexes of documents closest to the given one, excluding itself
+func (m *Model) Similar(doc int, n int, threshold float64) QueryResult {
+	q := fmt.Sprintf("similar to %d", doc)
+	if m.Matrix == nil {
+		return QueryResult{Query: q, Err: fmt.Errorf("model is not trained")}
+	}
+	_, docs := m.Matrix.Dims()
+	if doc < 0 || doc >= docs {
+		return QueryResult{Query: q, Err: fmt.Errorf("unknown document %d", doc)}
+	}
+
+	qr := QueryResult{Query: q, Matched: make([]int, 0), Similarities: make([]float64, 0)}
+	if m.normalised == nil {
+		return qr
+	}
+	vector := m.normalised.RawRowView(doc)
+	for i := 0; i < docs; i++ {
+		if i == doc {
+			continue
+		}
+		if s := dot(vector, m.normalised.RawRowView(i)); s >= threshold {
+			qr.Matched = append(qr.Matched, i)
+			qr.Similarities = append(qr.Similarities, s)
+		}
+	}
+	sort.Sort(&qr)
+
+	if len(qr.Matched) > n {
+		qr.Matched = qr.Matched[0:n]
+		qr.Similarities = qr.Similarities[0:n]
+	}
+
+	return qr
+}
@@ -0,0 +1,37 @@
+package nlp
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestSimilar(t *testing.T) {
+	c := NewCorpus()
+	if err := c.Load("../../books", regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	m := NewLSIModel()
+	if err := m.Train(&c); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+
+	qr := m.Similar(0, 5, 0.0)
+	if qr.Err != nil {
+		t.Fatalf("error finding similar documents %s", qr.Err.Error())
+	}
+	for i, doc := range qr.Matched {
+		if doc == 0 {
+			t.Errorf("expected document not to be similar to itself")
+		}
+		if i > 0 && qr.Similarities[i] > qr.Similarities[i-1] {
+			t.Errorf("expected similar documents ordered by similarity, got: %v", qr.Similarities)
+		}
+	}
+
+	if qr := m.Similar(1, 1, 0.0); len(qr.Matched) != 1 {
+		t.Errorf("expected 1 similar document, got: %d", len(qr.Matched))
+	}
+	if qr := m.Similar(4, 1, 0.0); qr.Err == nil {
+		t.Errorf("expected error for unknown document")
+	}
+}
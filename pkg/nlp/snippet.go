@@ -0,0 +1,62 @@
+package nlp
+
+import (
+	"strings"
+
+	"github.com/james-bowman/nlp"
+)
+
+// Snippet returns the passage of given number of words from the document that contains most of the query's terms
+func (m *Model) Snippet(doc int, q string, words int) (string, error) {
+	content, err := m.Corpus.Read(doc)
+	if err != nil {
+		return "", err
+	}
+
+	terms := make(map[string]bool)
+	text, _ := m.expand(q)
+	vectoriser, _ := m.Pipeline.Vectoriser.(*nlp.CountVectoriser)
+	for _, term := range m.tokenise(text) {
+		if vectoriser == nil || !vectoriser.StopWords[term] {
+			terms[term] = true
+		}
+	}
+
+	positions := wordPattern.FindAllStringIndex(content, -1)
+	if len(positions) == 0 || words < 1 {
+		return "", nil
+	}
+	if words > len(positions) {
+		words = len(positions)
+	}
+
+	hits := make([]int, len(positions))
+	for i, p := range positions {
+		if terms[strings.ToLower(content[p[0]:p[1]])] {
+			hits[i] = 1
+		}
+	}
+
+	// slide the window of words along the content, keeping the first one with most hits
+	best, count := 0, 0
+	for i := 0; i < words; i++ {
+		count += hits[i]
+	}
+	bestCount := count
+	for start := 1; start+words <= len(positions); start++ {
+		count += hits[start+words-1] - hits[start-1]
+		if count > bestCount {
+			best, bestCount = start, count
+		}
+	}
+
+	first, last := positions[best], positions[best+words-1]
+	snippet := strings.Join(strings.Fields(content[first[0]:last[1]]), " ")
+	if best > 0 {
+		snippet = "..." + snippet
+	}
+	if best+words < len(positions) {
+		snippet += "..."
+	}
+	return snippet, nil
+}
@@ -0,0 +1,39 @@
+package nlp
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSnippet(t *testing.T) {
+	c := NewCorpus()
+	if err := c.Load("../../books", regexp.MustCompile("Grand Teton")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	m := NewLSIModelWith(1, stopWords...)
+	if err := m.Train(&c); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+
+	snippet, err := m.Snippet(0, "cascade canyon trail", 12)
+	if err != nil {
+		t.Fatalf("error reading snippet %s", err.Error())
+	}
+	if !strings.HasPrefix(snippet, "...") || !strings.HasSuffix(snippet, "...") {
+		t.Errorf("expected snippet from the middle of the document, got: %q", snippet)
+	}
+	lower := strings.ToLower(snippet)
+	for _, term := range []string{"cascade", "canyon", "trail"} {
+		if !strings.Contains(lower, term) {
+			t.Errorf("expected snippet to contain %q, got: %q", term, snippet)
+		}
+	}
+	if words := len(strings.Fields(strings.Trim(snippet, "."))); words > 12 {
+		t.Errorf("expected snippet of at most 12 words, got: %d", words)
+	}
+
+	if metadata := c.GetMetadata(0); metadata["size"] != "50314" || metadata["modified"] == "" {
+		t.Errorf("expected file metadata, got: %v", metadata)
+	}
+}
@@ -0,0 +1,143 @@
+package nlp
+
+import (
+	"math"
+	"strings"
+
+	"github.com/james-bowman/nlp"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Suggest corrects query terms missing from the vocabulary to the closest known terms by edit distance
+// weighted by the number of documents they appear in; it returns empty string when there is nothing to correct
+func (m *Model) Suggest(q string) string {
+	vectoriser, ok := m.Pipeline.Vectoriser.(*nlp.CountVectoriser)
+	if !ok || len(m.frequencies) == 0 {
+		return ""
+	}
+
+	vocabulary := m.Vocabulary()
+	tokens := m.tokenise(q)
+	corrected := false
+
+	for i, token := range tokens {
+		if _, known := vectoriser.Vocabulary[token]; known || vectoriser.StopWords[token] {
+			continue
+		}
+		if m.synonyms != nil {
+			if _, ok := m.synonyms.rules[token]; ok {
+				continue
+			}
+		}
+		if correction := m.correct(token, vocabulary); correction != "" {
+			tokens[i] = correction
+			corrected = true
+		}
+	}
+
+	if !corrected {
+		return ""
+	}
+	return strings.Join(tokens, " ")
+}
+
+// correct finds the known term closest to the given one by edit distance less the log of the number of documents the term appears in,
+// so that an edit costs as much as a tenfold difference in frequency and common terms win over rare ones a little closer
+func (m *Model) correct(token string, vocabulary []string) string {
+	word := []rune(token)
+	maxDistance := 2
+	if len(word) <= 4 {
+		maxDistance = 1
+	}
+
+	best, bestWeight := "", math.Inf(1)
+	for i, term := range vocabulary {
+		candidate := []rune(term)
+		if abs(len(candidate)-len(word)) > maxDistance {
+			continue
+		}
+		d := editDistance(word, candidate, maxDistance+1)
+		if d > maxDistance {
+			continue
+		}
+		if weight := float64(d) - math.Log10(float64(1+m.frequencies[i])); weight < bestWeight {
+			best, bestWeight = term, weight
+		}
+	}
+	return best
+}
+
+// countFrequencies notes number of documents every term of the count matrix appears in
+func (m *Model) countFrequencies(counts mat.Matrix) {
+	rows, docs := counts.Dims()
+	m.frequencies = make([]int, rows)
+
+	if sparse, ok := counts.(nonZeroDoer); ok {
+		sparse.DoNonZero(func(i, j int, v float64) {
+			if v != 0 {
+				m.frequencies[i]++
+			}
+		})
+		return
+	}
+
+	for i := 0; i < rows; i++ {
+		for j := 0; j < docs; j++ {
+			if counts.At(i, j) != 0 {
+				m.frequencies[i]++
+			}
+		}
+	}
+}
+
+// editDistance returns Damerau-Levenshtein (optimal string alignment) distance of a and b,
+// or bound once it is certain the distance is not smaller than it
+func editDistance(a, b []rune, bound int) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && prev2[j-2]+1 < curr[j] {
+				curr[j] = prev2[j-2] + 1
+			}
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin >= bound {
+			return bound
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
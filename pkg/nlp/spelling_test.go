@@ -0,0 +1,86 @@
+package nlp
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"park", "park", 0},
+		{"prak", "park", 1},
+		{"parks", "park", 1},
+		{"prk", "park", 1},
+		{"tetno", "teton", 1},
+		{"mountian", "mountain", 1},
+		{"sausage", "garage", 3},
+	}
+	for _, c := range cases {
+		if d := editDistance([]rune(c.a), []rune(c.b), 10); d != c.want {
+			t.Errorf("expected distance between %q and %q to be %d, got: %d", c.a, c.b, c.want, d)
+		}
+	}
+
+	if d := editDistance([]rune("sausage"), []rune("garage"), 2); d != 2 {
+		t.Errorf("expected distance to be cut off at bound 2, got: %d", d)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	c := NewCorpus()
+	if err := c.Load("../../books", regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	m := NewLSIModel()
+	if err := m.Train(&c); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+
+	if s := m.Suggest("the tetno natoinal park"); s != "the teton national park" {
+		t.Errorf("expected misspelled terms to be corrected, got: %q", s)
+	}
+	if s := m.Suggest("national park"); s != "" {
+		t.Errorf("expected no suggestion for known terms, got: %q", s)
+	}
+
+	qr := m.Query("tetno", 1, 0.3)
+	if len(qr.Matched) != 0 || qr.Suggestion != "teton" || qr.Corrected {
+		t.Errorf("expected suggestion without rerunning the query, got: %+v", qr)
+	}
+
+	qr = m.Query("wild weekend", 1, 0.3)
+	if len(qr.Matched) != 1 || qr.Suggestion != "" {
+		t.Errorf("expected no suggestion for query matching better than it, got: %+v", qr)
+	}
+
+	m.AutoCorrect = true
+	qr = m.Query("tetno", 1, 0.3)
+	if len(qr.Matched) != 1 || !qr.Corrected || qr.Query != "tetno" {
+		t.Errorf("expected query to be rerun with its suggestion, got: %+v", qr)
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	m := NewLSIModel()
+	vocabulary := []string{"parka", "parks", "pork"}
+
+	// an edit costs as much as a tenfold difference in frequency
+	m.frequencies = []int{1, 2, 1}
+	if c := m.correct("parkss", vocabulary); c != "parks" {
+		t.Errorf("expected closest term, got: %q", c)
+	}
+	m.frequencies = []int{500, 2, 1}
+	if c := m.correct("parkss", vocabulary); c != "parka" {
+		t.Errorf("expected much more common term further away, got: %q", c)
+	}
+	m.frequencies = []int{1, 1, 30}
+	if c := m.correct("park", vocabulary); c != "pork" {
+		t.Errorf("expected more common term at the same distance, got: %q", c)
+	}
+	if c := m.correct("xyz", vocabulary); c != "" {
+		t.Errorf("expected no term too far away, got: %q", c)
+	}
+}
@@ -0,0 +1,151 @@
+package nlp
+
+import (
+	"time"
+
+	"github.com/stormcrows/qdox/pkg/store"
+	"gonum.org/v1/gonum/mat"
+)
+
+// decodingState is the key of state of the store noting the settings stored documents were decoded with
+const decodingState = "decoding"
+
+// UseStore keeps documents loaded into the corpus in the store, so later loads reuse those which have not changed
+// instead of decoding them again, and contents of released documents are read back from it
+func (c *Corpus) UseStore(s *store.Store) {
+	c.store = s
+}
+
+// Reused returns number of documents of the last load found unchanged in the store
+func (c *Corpus) Reused() int {
+	return c.reused
+}
+
+// storedDocuments looks documents of a load up in the store, collecting records of new and changed ones to write once it is done
+type storedDocuments struct {
+	store *store.Store
+	// valid is set if stored documents were decoded with the same settings, and so can be reused
+	valid   bool
+	records []store.Record
+	// previous records of documents looked up
+	previous map[string]store.Record
+	versions []store.Version
+	reused   int
+}
+
+// openStored starts a load of documents decoded according to the filter, finding nothing if the corpus has no store
+func (c *Corpus) openStored(filter Filter) (*storedDocuments, error) {
+	s := &storedDocuments{store: c.store, previous: make(map[string]store.Record)}
+	if s.store == nil {
+		return s, nil
+	}
+	state, err := s.store.State(decodingState)
+	s.valid = string(state) == decodingSettings(filter)
+	return s, err
+}
+
+// get returns the stored document if it has the same size and modification time as the file
+func (s *storedDocuments) get(path string, size int64, modified time.Time) (Document, bool) {
+	if s.store == nil || !s.valid {
+		return Document{}, false
+	}
+	r, ok, err := s.store.Get(path)
+	if err != nil || !ok {
+		return Document{}, false
+	}
+	s.previous[path] = r
+	if !r.Unchanged(size, modified) {
+		return Document{}, false
+	}
+
+	s.reused++
+	metadata := make(map[string]string, len(r.Metadata))
+	for key, value := range r.Metadata {
+		metadata[key] = value
+	}
+	return Document{content: r.Content, path: r.Path, hash: r.Hash, fingerprint: r.Fingerprint, metadata: metadata, since: r.Since}, true
+}
+
+// put notes the record of the new or updated document, which is unchanged if its content has the hash of the stored one,
+// keeping its vector and the time it was first seen, or otherwise replaces the stored one which is kept as its version
+func (s *storedDocuments) put(doc *Document, size int64, modified time.Time) {
+	if s.store == nil {
+		return
+	}
+	r := store.Record{
+		Path:        doc.path,
+		Content:     doc.content,
+		Hash:        doc.hash,
+		Fingerprint: doc.fingerprint,
+		Metadata:    doc.metadata,
+		Size:        size,
+		Modified:    modified,
+		Since:       modified,
+	}
+	if previous, ok := s.previous[doc.path]; ok && previous.Hash == doc.hash {
+		if !previous.Unchanged(size, modified) {
+			s.reused++
+		}
+		r.Since, r.Vector = previous.Since, previous.Vector
+	} else if ok {
+		s.versions = append(s.versions, store.Version{Record: previous, Replaced: modified})
+	}
+	doc.since = r.Since
+	s.records = append(s.records, r)
+}
+
+// nested starts noting records apart, such as of members of an archive which may fail to read, to merge once they are read
+func (s *storedDocuments) nested() *storedDocuments {
+	return &storedDocuments{store: s.store, valid: s.valid, previous: s.previous}
+}
+
+// merge notes records and versions noted apart
+func (s *storedDocuments) merge(nested *storedDocuments) {
+	s.records = append(s.records, nested.records...)
+	s.versions = append(s.versions, nested.versions...)
+	s.reused += nested.reused
+}
+
+// save writes noted records and versions they replace, and removes records of documents which were not loaded,
+// keeping them as versions removed now
+func (s *storedDocuments) save(documents []Document, filter Filter) error {
+	if s.store == nil {
+		return nil
+	}
+	if err := s.store.Put(s.records...); err != nil {
+		return err
+	}
+	kept := make(map[string]bool, len(documents))
+	for _, doc := range documents {
+		kept[doc.path] = true
+	}
+	removed, err := s.store.Retain(kept)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, r := range removed {
+		s.versions = append(s.versions, store.Version{Record: r, Replaced: now})
+	}
+	if err := s.store.AddVersions(s.versions...); err != nil {
+		return err
+	}
+	return s.store.SetState(decodingState, []byte(decodingSettings(filter)))
+}
+
+// decodingSettings are settings of the filter affecting decoded contents of documents
+func decodingSettings(filter Filter) string {
+	return filter.Encoding + " " + filter.Normalisation
+}
+
+// storeVectors writes LSI vectors of documents, which are columns of the matrix, to the store
+func (c *Corpus) storeVectors(vectors mat.Matrix) error {
+	if c.store == nil {
+		return nil
+	}
+	byPath := make(map[string][]float64, len(c.documents))
+	for i, doc := range c.documents {
+		byPath[doc.path] = mat.Col(nil, i, vectors)
+	}
+	return c.store.PutVectors(byPath)
+}
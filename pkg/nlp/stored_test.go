@@ -0,0 +1,108 @@
+package nlp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stormcrows/qdox/pkg/store"
+)
+
+func TestLoadWithStore(t *testing.T) {
+	books, err := filepath.Glob("../../books/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string, len(books))
+	for _, book := range books {
+		content, err := ioutil.ReadFile(book)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[filepath.Base(book)] = string(content)
+	}
+	dir := writeFiles(t, files)
+	defer os.RemoveAll(dir)
+
+	s, err := store.Open(filepath.Join(dir, "qdox.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	filter := Filter{Pattern: regexp.MustCompile("\\.txt$")}
+	load := func() *Corpus {
+		c := NewCorpus()
+		c.UseStore(s)
+		if err := c.LoadWith(dir, filter); err != nil {
+			t.Fatalf("error reading folder %s", err.Error())
+		}
+		return &c
+	}
+
+	if c := load(); c.Reused() != 0 || c.Len() != len(books) {
+		t.Errorf("expected %d new documents, got %d of %d reused", len(books), c.Reused(), c.Len())
+	}
+	if n, _ := s.Len(); n != len(books) {
+		t.Errorf("expected %d stored documents, got: %d", len(books), n)
+	}
+
+	// touched file is unchanged by its hash, rewritten one has changed and removed one is dropped from the store
+	later := time.Now().Add(time.Hour)
+	touched, rewritten, removed := filepath.Join(dir, filepath.Base(books[0])), filepath.Join(dir, filepath.Base(books[1])), filepath.Join(dir, filepath.Base(books[2]))
+	if err := os.Chtimes(touched, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(rewritten, []byte("rewritten national park"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+
+	c := load()
+	if c.Reused() != len(books)-2 {
+		t.Errorf("expected %d reused documents, got: %d", len(books)-2, c.Reused())
+	}
+	if r, ok, err := s.Get(rewritten); err != nil || !ok || r.Content != "rewritten national park" {
+		t.Errorf("expected stored content of the rewritten file to be updated, got %q", r.Content)
+	}
+	if r, _, _ := s.Get(touched); !r.Modified.Equal(later) {
+		t.Errorf("expected stored modification time of the touched file to be updated, got %s", r.Modified)
+	}
+	if _, ok, _ := s.Get(removed); ok {
+		t.Errorf("expected removed file to be dropped from the store")
+	}
+
+	// training stores vectors, and contents of released documents are read from the store
+	m := NewLSIModel()
+	if err := m.Train(c); err != nil {
+		t.Fatal(err)
+	}
+	dims, _ := m.Matrix.Dims()
+	for i, path := range c.Paths() {
+		if r, _, _ := s.Get(path); len(r.Vector) != dims {
+			t.Errorf("expected vector of %d components for document %d, got: %v", dims, i, r.Vector)
+		}
+	}
+	if err := os.Remove(rewritten); err != nil {
+		t.Fatal(err)
+	}
+	for i, path := range c.Paths() {
+		if path != rewritten {
+			continue
+		}
+		if content, err := c.Read(i); err != nil || content != "rewritten national park" {
+			t.Errorf("expected content of the removed file read from the store, got %q, %v", content, err)
+		}
+	}
+
+	// documents decoded otherwise are not reused
+	filter.Normalisation = "NFKC"
+	if c := load(); c.Reused() != 0 {
+		t.Errorf("expected no documents reused with other normalisation, got: %d", c.Reused())
+	}
+}
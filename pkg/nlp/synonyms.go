@@ -0,0 +1,113 @@
+package nlp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// synonyms holds Solr-style rules keyed by the phrase they match
+type synonyms struct {
+	rules   map[string]synonymRule
+	longest int
+}
+
+// synonymRule either adds equivalent phrases to the matched one or replaces it with them
+type synonymRule struct {
+	phrases [][]string
+	replace bool
+}
+
+// parseSynonyms reads Solr-style synonym lines, either equivalent phrases ("couch, sofa, divan")
+// or explicit mappings replacing phrases on the left with ones on the right ("i-pod, i pod => ipod")
+func parseSynonyms(r io.Reader, tokenise func(string) []string) (*synonyms, error) {
+	s := &synonyms{make(map[string]synonymRule), 0}
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		sides := strings.Split(text, "=>")
+		if len(sides) > 2 {
+			return nil, fmt.Errorf("synonyms line %d: more than one \"=>\"", line)
+		}
+
+		left := splitPhrases(sides[0], tokenise)
+		right := left
+		if len(sides) == 2 {
+			right = splitPhrases(sides[1], tokenise)
+		}
+		if len(left) == 0 || len(right) == 0 {
+			return nil, fmt.Errorf("synonyms line %d: expected comma separated phrases", line)
+		}
+
+		for _, phrase := range left {
+			rule := s.rules[strings.Join(phrase, " ")]
+			rule.replace = rule.replace || len(sides) == 2
+			rule.phrases = append(rule.phrases, right...)
+			s.rules[strings.Join(phrase, " ")] = rule
+			if len(phrase) > s.longest {
+				s.longest = len(phrase)
+			}
+		}
+	}
+
+	return s, scanner.Err()
+}
+
+func splitPhrases(side string, tokenise func(string) []string) [][]string {
+	phrases := make([][]string, 0)
+	for _, phrase := range strings.Split(side, ",") {
+		if tokens := tokenise(phrase); len(tokens) > 0 {
+			phrases = append(phrases, tokens)
+		}
+	}
+	return phrases
+}
+
+// rewrite applies rules to the longest phrases matching query tokens,
+// returning rewritten tokens along with terms of the phrases it added
+func (s *synonyms) rewrite(tokens []string) ([]string, []string) {
+	rewritten := make([]string, 0, len(tokens))
+	added := make([]string, 0)
+
+	for i := 0; i < len(tokens); {
+		size := s.longest
+		if len(tokens)-i < size {
+			size = len(tokens) - i
+		}
+
+		matched := false
+		for ; size > 0; size-- {
+			phrase := strings.Join(tokens[i:i+size], " ")
+			rule, ok := s.rules[phrase]
+			if !ok {
+				continue
+			}
+			if !rule.replace {
+				rewritten = append(rewritten, tokens[i:i+size]...)
+			}
+			for _, p := range rule.phrases {
+				if strings.Join(p, " ") == phrase && !rule.replace {
+					continue
+				}
+				rewritten = append(rewritten, p...)
+				added = append(added, strings.Join(p, " "))
+			}
+			i += size
+			matched = true
+			break
+		}
+
+		if !matched {
+			rewritten = append(rewritten, tokens[i])
+			i++
+		}
+	}
+
+	return rewritten, added
+}
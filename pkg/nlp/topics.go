@@ -0,0 +1,74 @@
+package nlp
+
+import (
+	"fmt"
+	"math"
+)
+
+// Topic describes a single LSI dimension by its highest weighted terms and most strongly loaded documents
+type Topic struct {
+	Terms     []string
+	Weights   []float64
+	Variance  float64
+	Documents []int
+	Loadings  []float64
+}
+
+// Topics lists every LSI dimension of the trained model with n terms and documents loaded on it
+//
+// Variance is the share of the TF-IDF matrix's total squared norm captured by the dimension.
+// Signs of SVD components are arbitrary, so each one is oriented to make its strongest term weight positive.
+func (m *Model) Topics(terms int, documents int) ([]Topic, error) {
+	components := m.components()
+	if m.Matrix == nil || components == nil {
+		return nil, fmt.Errorf("model is not trained")
+	}
+
+	vocabulary := m.Vocabulary()
+	vectors := m.documentVectors()
+	dims, docs := m.Matrix.Dims()
+	rows, _ := components.Dims()
+	topics := make([]Topic, dims)
+
+	for k := 0; k < dims; k++ {
+		sign, strongest := 1.0, 0.0
+		for i := 0; i < rows; i++ {
+			if w := components.At(i, k); math.Abs(w) > strongest {
+				strongest = math.Abs(w)
+				sign = math.Copysign(1, w)
+			}
+		}
+
+		weights := make([]float64, rows)
+		for i := range weights {
+			weights[i] = sign * components.At(i, k)
+		}
+		topic := Topic{Terms: make([]string, 0), Weights: make([]float64, 0)}
+		for _, i := range topIndexes(weights, terms) {
+			topic.Terms = append(topic.Terms, vocabulary[i])
+			topic.Weights = append(topic.Weights, weights[i])
+		}
+
+		energy := 0.0
+		for j := 0; j < docs; j++ {
+			energy += m.Matrix.At(k, j) * m.Matrix.At(k, j)
+		}
+		if m.energy > 0 {
+			topic.Variance = energy / m.energy
+		}
+
+		loadings := make([]float64, docs)
+		for j := range loadings {
+			loadings[j] = sign * vectors[j][k]
+		}
+		topic.Documents = topIndexes(loadings, documents)
+		topic.Loadings = make([]float64, len(topic.Documents))
+		for i, j := range topic.Documents {
+			topic.Loadings[i] = loadings[j]
+		}
+
+		topics[k] = topic
+	}
+
+	return topics, nil
+}
@@ -0,0 +1,47 @@
+package nlp
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestTopics(t *testing.T) {
+	c := NewCorpus()
+	if err := c.Load("../../books", regexp.MustCompile("\\.txt")); err != nil {
+		t.Fatalf("error reading folder %s", err.Error())
+	}
+	m := NewLSIModel()
+	if err := m.Train(&c); err != nil {
+		t.Fatalf("error training model %s", err.Error())
+	}
+
+	topics, err := m.Topics(5, 2)
+	if err != nil {
+		t.Fatalf("error listing topics %s", err.Error())
+	}
+
+	if len(topics) != 4 {
+		t.Fatalf("expected a topic per each of 4 dimensions, got: %d", len(topics))
+	}
+
+	total := 0.0
+	for i, topic := range topics {
+		if len(topic.Terms) != 5 || len(topic.Weights) != 5 {
+			t.Errorf("expected 5 terms for topic %d, got: %v", i, topic.Terms)
+		}
+		if topic.Weights[0] <= 0 {
+			t.Errorf("expected strongest term of topic %d to have positive weight, got: %f", i, topic.Weights[0])
+		}
+		if len(topic.Documents) > 2 {
+			t.Errorf("expected at most 2 documents for topic %d, got: %d", i, len(topic.Documents))
+		}
+		if i > 0 && topic.Variance > topics[i-1].Variance {
+			t.Errorf("expected topics ordered by variance, got: %f after %f", topic.Variance, topics[i-1].Variance)
+		}
+		total += topic.Variance
+	}
+
+	if total <= 0 || total > 1.0000001 {
+		t.Errorf("expected variance explained to be a fraction, got: %f", total)
+	}
+}
@@ -0,0 +1,142 @@
+package nlp
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/james-bowman/nlp"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Reduction configures fitting of the SVD stage of the pipeline, which dominates time and memory of training large corpora.
+// Zero value decomposes the whole TF-IDF matrix exactly
+type Reduction struct {
+	// Randomised approximates the SVD from projection of the matrix onto random directions instead of decomposing it
+	Randomised bool
+	// Oversampling is the number of random directions beyond the number of dimensions, improving accuracy of randomised SVD
+	Oversampling int
+	// PowerIterations refine randomised SVD of matrices with slowly decaying spectrum, at a cost of two passes over the matrix each
+	PowerIterations int
+	// Sample is the number of randomly chosen documents the SVD is fitted to, with the rest folded in, 0 fits all of them
+	Sample int
+	// Seed makes random directions and the sample reproducible
+	Seed int64
+}
+
+// DefaultRandomised returns randomised reduction accurate enough for LSI of typical corpora
+func DefaultRandomised() Reduction {
+	return Reduction{Randomised: true, Oversampling: 10, PowerIterations: 2}
+}
+
+// TrainingStats reports how long stages of training took and how much memory it used.
+// Memory is measured for the whole process, so it includes other models trained at the same time
+type TrainingStats struct {
+	Documents int
+	Terms     int
+	// Fitted is the number of documents the SVD was fitted to, the rest were folded in
+	Fitted    int
+	Vectorise time.Duration
+	Weigh     time.Duration
+	Reduce    time.Duration
+	Index     time.Duration
+	// IndexRestored is set if the approximate nearest neighbour index was restored from the store instead of built
+	IndexRestored bool
+	Total         time.Duration
+	// Allocated is the number of bytes allocated during training, HeapInUse and Sys are bytes of heap in use
+	// and obtained from the system once it is done
+	Allocated uint64
+	HeapInUse uint64
+	Sys       uint64
+}
+
+func (s TrainingStats) String() string {
+	restored := ""
+	if s.IndexRestored {
+		restored = " (restored)"
+	}
+	return fmt.Sprintf("trained %d documents (%d fitted), %d terms in %s: vectorise=%s weigh=%s reduce=%s index=%s%s, allocated=%s heap=%s sys=%s",
+		s.Documents, s.Fitted, s.Terms, s.Total.Round(time.Millisecond),
+		s.Vectorise.Round(time.Millisecond), s.Weigh.Round(time.Millisecond), s.Reduce.Round(time.Millisecond), s.Index.Round(time.Millisecond), restored,
+		formatBytes(s.Allocated), formatBytes(s.HeapInUse), formatBytes(s.Sys))
+}
+
+// Stats returns statistics of the last training of the model
+func (m *Model) Stats() TrainingStats {
+	return m.stats
+}
+
+// fitReduction fits the reduction stage to the matrix, or to sampled documents of it, returning all documents projected onto it
+func (m *Model) fitReduction(transformer nlp.Transformer, matrix mat.Matrix) (mat.Matrix, error) {
+	terms, docs := matrix.Dims()
+	m.stats.Fitted = docs
+
+	svd, ok := transformer.(*nlp.TruncatedSVD)
+	if !ok || (!m.Reduction.Randomised && (m.Reduction.Sample <= 0 || m.Reduction.Sample >= docs)) {
+		return transformer.FitTransform(matrix)
+	}
+
+	rng := rand.New(rand.NewSource(m.Reduction.Seed))
+	columns := sample(rng, docs, m.Reduction.Sample)
+	if columns != nil {
+		m.stats.Fitted = len(columns)
+	}
+
+	if m.Reduction.Randomised {
+		components, err := randomisedSVD(matrix, columns, svd.K, m.Reduction.Oversampling, m.Reduction.PowerIterations, rng)
+		if err != nil {
+			return nil, err
+		}
+		svd.Components = components
+	} else {
+		position := make([]int, docs)
+		for j := range position {
+			position[j] = -1
+		}
+		for c, j := range columns {
+			position[j] = c
+		}
+		sampled := mat.NewDense(terms, len(columns), nil)
+		eachNonZero(matrix, func(i, j int, v float64) {
+			if position[j] >= 0 {
+				sampled.Set(i, position[j], v)
+			}
+		})
+		if _, err := svd.FitTransform(sampled); err != nil {
+			return nil, err
+		}
+	}
+	return project(svd.Components, matrix), nil
+}
+
+// sample returns sorted indexes of size randomly chosen documents, or nil if size does not limit them
+func sample(rng *rand.Rand, docs int, size int) []int {
+	if size <= 0 || size >= docs {
+		return nil
+	}
+	columns := rng.Perm(docs)[:size]
+	sort.Ints(columns)
+	return columns
+}
+
+// measure returns memory statistics of the process
+func measure() runtime.MemStats {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats
+}
+
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,310 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	documentsBucket = []byte("documents")
+	versionsBucket  = []byte("versions")
+	vectorsBucket   = []byte("vectors")
+	stateBucket     = []byte("state")
+)
+
+// Record is a document kept in the store, keyed by its path
+type Record struct {
+	Path        string
+	Content     string
+	Hash        string
+	Fingerprint uint64
+	Metadata    map[string]string `json:",omitempty"`
+	Size        int64
+	Modified    time.Time
+	// Since is the modification time of the file when its content was first seen, kept by later modifications not changing it
+	Since time.Time
+	// Vector is the LSI vector of the document as of the last training, if any, kept apart from the record
+	// so that training does not rewrite contents
+	Vector []float64 `json:"-"`
+}
+
+// Unchanged tells whether the file of the record still has its size and modification time
+func (r Record) Unchanged(size int64, modified time.Time) bool {
+	return r.Size == size && r.Modified.Equal(modified)
+}
+
+// Version is a record replaced by one of different content, or removed, at given time
+type Version struct {
+	Record
+	Replaced time.Time
+}
+
+// Store keeps records of documents, their past versions and state of the index in a bbolt file, safe for concurrent use
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens the store in the file, creating it if it does not exist.
+// The file is locked while open, so opening it again waits up to a second before failing
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{documentsBucket, versionsBucket, vectorsBucket, stateBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close releases the file
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Get returns the record of the document at the path, if there is one
+func (s *Store) Get(path string) (Record, bool, error) {
+	r := Record{}
+	found := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(documentsBucket).Get([]byte(path))
+		if value == nil {
+			return nil
+		}
+		found = true
+		if err := json.Unmarshal(value, &r); err != nil {
+			return err
+		}
+		r.Vector = getVector(tx, []byte(path))
+		return nil
+	})
+	return r, found, err
+}
+
+// Put writes the records along with their vectors in a single transaction, replacing previous records of their paths
+// and dropping vectors of records without one
+func (s *Store) Put(records ...Record) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(documentsBucket)
+		for _, r := range records {
+			value, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(r.Path), value); err != nil {
+				return err
+			}
+			if err := putVector(tx, []byte(r.Path), r.Vector); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PutVectors replaces vectors of records by their paths, leaving the records as they are
+func (s *Store) PutVectors(vectors map[string][]float64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for path, v := range vectors {
+			if err := putVector(tx, []byte(path), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete removes records of the paths
+func (s *Store) Delete(paths ...string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(documentsBucket)
+		for _, path := range paths {
+			if err := b.Delete([]byte(path)); err != nil {
+				return err
+			}
+			if err := tx.Bucket(vectorsBucket).Delete([]byte(path)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Retain removes records of paths other than the kept ones, returning removed records with their vectors
+func (s *Store) Retain(kept map[string]bool) ([]Record, error) {
+	removed := make([]Record, 0)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(documentsBucket)
+		// records are collected first, as deleting moves the cursor
+		if err := b.ForEach(func(k, value []byte) error {
+			if kept[string(k)] {
+				return nil
+			}
+			r := Record{}
+			if err := json.Unmarshal(value, &r); err != nil {
+				return err
+			}
+			r.Vector = getVector(tx, k)
+			removed = append(removed, r)
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, r := range removed {
+			if err := b.Delete([]byte(r.Path)); err != nil {
+				return err
+			}
+			if err := tx.Bucket(vectorsBucket).Delete([]byte(r.Path)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// AddVersions keeps past versions of documents along with their vectors
+func (s *Store) AddVersions(versions ...Version) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(versionsBucket)
+		for _, v := range versions {
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			key := versionKey(v.Path, v.Since)
+			if err := b.Put(key, value); err != nil {
+				return err
+			}
+			if err := putVector(tx, key, v.Vector); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Versions returns past versions of the document at the path, oldest first
+func (s *Store) Versions(path string) ([]Version, error) {
+	versions := make([]Version, 0)
+	prefix := append([]byte(path), 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(versionsBucket).Cursor()
+		for k, value := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, value = c.Next() {
+			v := Version{}
+			if err := json.Unmarshal(value, &v); err != nil {
+				return err
+			}
+			v.Vector = getVector(tx, k)
+			versions = append(versions, v)
+		}
+		return nil
+	})
+	return versions, err
+}
+
+// ForEachVersion calls fn with past versions of all documents in order of their paths, oldest first, until it returns an error
+func (s *Store) ForEachVersion(fn func(v Version) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(versionsBucket).ForEach(func(k, value []byte) error {
+			v := Version{}
+			if err := json.Unmarshal(value, &v); err != nil {
+				return err
+			}
+			v.Vector = getVector(tx, k)
+			return fn(v)
+		})
+	})
+}
+
+// versionKey orders versions of a path by the time their content was first seen
+func versionKey(path string, since time.Time) []byte {
+	key := make([]byte, len(path)+9)
+	copy(key, path)
+	binary.BigEndian.PutUint64(key[len(path)+1:], uint64(since.UnixNano()))
+	return key
+}
+
+// ForEach calls fn with records in order of their paths, until it returns an error
+func (s *Store) ForEach(fn func(r Record) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(documentsBucket).ForEach(func(k, value []byte) error {
+			r := Record{}
+			if err := json.Unmarshal(value, &r); err != nil {
+				return err
+			}
+			r.Vector = getVector(tx, k)
+			return fn(r)
+		})
+	})
+}
+
+// Len returns number of records
+func (s *Store) Len() (int, error) {
+	n := 0
+	err := s.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(documentsBucket).Stats().KeyN
+		return nil
+	})
+	return n, err
+}
+
+// SetState writes state of the index under the key, such as settings documents were loaded with
+func (s *Store) SetState(key string, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(stateBucket).Put([]byte(key), value)
+	})
+}
+
+// State returns state of the index under the key, nil if there is none
+func (s *Store) State(key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// values are only valid within the transaction
+		if v := tx.Bucket(stateBucket).Get([]byte(key)); v != nil {
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	return value, err
+}
+
+// putVector writes the vector under the key of its record or version, or deletes it if it is nil
+func putVector(tx *bolt.Tx, key []byte, v []float64) error {
+	b := tx.Bucket(vectorsBucket)
+	if v == nil {
+		return b.Delete(key)
+	}
+	value := make([]byte, 8*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint64(value[8*i:], math.Float64bits(x))
+	}
+	return b.Put(key, value)
+}
+
+// getVector reads the vector under the key of its record or version, nil if there is none
+func getVector(tx *bolt.Tx, key []byte) []float64 {
+	value := tx.Bucket(vectorsBucket).Get(key)
+	if value == nil {
+		return nil
+	}
+	v := make([]float64, len(value)/8)
+	for i := range v {
+		v[i] = math.Float64frombits(binary.LittleEndian.Uint64(value[8*i:]))
+	}
+	return v
+}
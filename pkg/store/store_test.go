@@ -0,0 +1,176 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(filepath.Join(dir, "qdox.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	records := []Record{
+		{Path: "a.txt", Content: "first", Hash: "1", Size: 5, Modified: modified, Metadata: map[string]string{"author": "me"}},
+		{Path: "b.txt", Content: "second", Hash: "2", Size: 6, Modified: modified, Vector: []float64{0.5, -0.5}},
+		{Path: "c.txt", Content: "third", Hash: "3", Size: 5, Modified: modified},
+	}
+	if err := s.Put(records...); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetState("decoding", []byte("utf-8")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// records outlive the store being reopened
+	if s, err = Open(filepath.Join(dir, "qdox.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	got, ok, err := s.Get("b.txt")
+	if err != nil || !ok {
+		t.Fatalf("expected record of b.txt, got %t, %v", ok, err)
+	}
+	if !reflect.DeepEqual(records[1], got) {
+		t.Errorf("expected %+v, got: %+v", records[1], got)
+	}
+	if !got.Unchanged(6, modified) || got.Unchanged(6, modified.Add(time.Second)) || got.Unchanged(7, modified) {
+		t.Errorf("record should be unchanged only for its size and modification time")
+	}
+	if _, ok, _ := s.Get("d.txt"); ok {
+		t.Errorf("expected no record of d.txt")
+	}
+	if state, err := s.State("decoding"); err != nil || string(state) != "utf-8" {
+		t.Errorf("expected state utf-8, got: %q, %v", state, err)
+	}
+	if state, err := s.State("missing"); err != nil || state != nil {
+		t.Errorf("expected no state, got: %q, %v", state, err)
+	}
+
+	removed, err := s.Retain(map[string]bool{"a.txt": true, "c.txt": true})
+	if err != nil || len(removed) != 1 || removed[0].Path != "b.txt" {
+		t.Errorf("expected to remove record of b.txt, got: %v, %v", removed, err)
+	}
+	if err := s.Delete("c.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := make([]string, 0)
+	if err := s.ForEach(func(r Record) error {
+		paths = append(paths, r.Path)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]string{"a.txt"}, paths) {
+		t.Errorf("expected records of [a.txt], got: %v", paths)
+	}
+	if n, err := s.Len(); err != nil || n != 1 {
+		t.Errorf("expected 1 record, got: %d, %v", n, err)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(filepath.Join(dir, "qdox.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	versions := []Version{
+		{Record{Path: "a.txt", Hash: "2", Since: day(2)}, day(3)},
+		{Record{Path: "a.txt", Hash: "1", Since: day(1)}, day(2)},
+		{Record{Path: "a.txt.bak", Hash: "3", Since: day(1)}, day(4)},
+	}
+	if err := s.AddVersions(versions...); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Versions("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]Version{versions[1], versions[0]}, got) {
+		t.Errorf("expected versions of a.txt oldest first, got: %+v", got)
+	}
+	if got, _ := s.Versions("b.txt"); len(got) != 0 {
+		t.Errorf("expected no versions of b.txt, got: %+v", got)
+	}
+
+	hashes := ""
+	if err := s.ForEachVersion(func(v Version) error {
+		hashes += v.Hash
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if hashes != "123" {
+		t.Errorf("expected versions of all paths in order, got hashes: %s", hashes)
+	}
+}
+
+func TestVectors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(filepath.Join(dir, "qdox.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Put(Record{Path: "a.txt", Content: "first", Vector: []float64{1, 2}}, Record{Path: "b.txt", Content: "second"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutVectors(map[string][]float64{"b.txt": {0.5, -0.25}}); err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _ := s.Get("b.txt"); r.Content != "second" || !reflect.DeepEqual([]float64{0.5, -0.25}, r.Vector) {
+		t.Errorf("expected vector of b.txt to be updated apart from its content, got: %+v", r)
+	}
+
+	// records written without vectors drop stale ones
+	if err := s.Put(Record{Path: "a.txt", Content: "changed"}); err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _ := s.Get("a.txt"); r.Vector != nil {
+		t.Errorf("expected vector of a.txt to be dropped, got: %v", r.Vector)
+	}
+
+	removed, err := s.Retain(map[string]bool{"a.txt": true})
+	if err != nil || len(removed) != 1 || !reflect.DeepEqual([]float64{0.5, -0.25}, removed[0].Vector) {
+		t.Errorf("expected removed record of b.txt with its vector, got: %+v, %v", removed, err)
+	}
+	if err := s.Put(Record{Path: "b.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _ := s.Get("b.txt"); r.Vector != nil {
+		t.Errorf("expected vector of removed b.txt to be dropped, got: %v", r.Vector)
+	}
+}
@@ -0,0 +1,234 @@
+package suggest
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// Trie is a prefix tree of weighted entries, safe for concurrent use
+type Trie struct {
+	mu   sync.RWMutex
+	root *node
+	size int
+	// limit bounds number of entries if positive, evicting the lowest weighted one to insert another
+	limit  int
+	lowest entries
+	byText map[string]*entry
+}
+
+// Completion is an entry matching the prefix along with its weight
+type Completion struct {
+	Text   string
+	Weight int
+}
+
+type node struct {
+	children map[rune]*node
+	weight   int
+	terminal bool
+	// best is the highest weight of entries in the subtree, used to visit most promising branches first
+	best int
+}
+
+// NewTrie returns an empty prefix tree
+func NewTrie() *Trie {
+	return &Trie{root: newNode()}
+}
+
+// NewBoundedTrie returns an empty prefix tree keeping up to limit entries, which evicts the lowest weighted entry
+// to insert another one once it is full
+func NewBoundedTrie(limit int) *Trie {
+	return &Trie{root: newNode(), limit: limit, byText: make(map[string]*entry)}
+}
+
+func newNode() *node {
+	return &node{children: make(map[rune]*node)}
+}
+
+// Len returns number of entries in the tree
+func (t *Trie) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.size
+}
+
+// Add increases weight of the entry by delta, inserting it when missing
+func (t *Trie) Add(text string, delta int) {
+	if text == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.limit > 0 {
+		if e, ok := t.byText[text]; ok {
+			e.weight += delta
+			heap.Fix(&t.lowest, e.index)
+		} else {
+			if t.size >= t.limit {
+				t.remove(heap.Pop(&t.lowest).(*entry).text)
+			}
+			e := &entry{text: text, weight: delta}
+			heap.Push(&t.lowest, e)
+			t.byText[text] = e
+		}
+	}
+
+	path := []*node{t.root}
+	n := t.root
+	for _, r := range text {
+		child, ok := n.children[r]
+		if !ok {
+			child = newNode()
+			n.children[r] = child
+		}
+		n = child
+		path = append(path, n)
+	}
+
+	if !n.terminal {
+		n.terminal = true
+		t.size++
+	}
+	n.weight += delta
+	for _, p := range path {
+		if n.weight > p.best {
+			p.best = n.weight
+		}
+	}
+}
+
+// remove deletes the entry, pruning branches left empty and lowering best weights of the rest of its path
+func (t *Trie) remove(text string) {
+	path := []*node{t.root}
+	n := t.root
+	for _, r := range text {
+		child, ok := n.children[r]
+		if !ok {
+			return
+		}
+		n = child
+		path = append(path, n)
+	}
+	if !n.terminal {
+		return
+	}
+	n.terminal, n.weight = false, 0
+	t.size--
+	delete(t.byText, text)
+
+	runes := []rune(text)
+	for i := len(path) - 1; i >= 0; i-- {
+		p := path[i]
+		p.best = 0
+		if p.terminal {
+			p.best = p.weight
+		}
+		for _, child := range p.children {
+			if child.best > p.best {
+				p.best = child.best
+			}
+		}
+		if i > 0 && !p.terminal && len(p.children) == 0 {
+			delete(path[i-1].children, runes[i-1])
+		}
+	}
+}
+
+// Complete returns up to limit entries starting with prefix, highest weighted first
+func (t *Trie) Complete(prefix string, limit int) []Completion {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	completions := make([]Completion, 0)
+	n := t.root
+	for _, r := range prefix {
+		child, ok := n.children[r]
+		if !ok {
+			return completions
+		}
+		n = child
+	}
+
+	// best first search: subtrees are expanded in order of their best weight,
+	// entries are emitted once no remaining subtree can outweigh them
+	h := &candidates{{n, prefix, n.best, false}}
+	for h.Len() > 0 && len(completions) < limit {
+		c := heap.Pop(h).(candidate)
+		if c.entry {
+			completions = append(completions, Completion{c.text, c.weight})
+			continue
+		}
+		if c.node.terminal {
+			heap.Push(h, candidate{c.node, c.text, c.node.weight, true})
+		}
+		for r, child := range c.node.children {
+			heap.Push(h, candidate{child, c.text + string(r), child.best, false})
+		}
+	}
+
+	return completions
+}
+
+type candidate struct {
+	node   *node
+	text   string
+	weight int
+	entry  bool
+}
+
+type candidates []candidate
+
+func (c candidates) Len() int { return len(c) }
+
+func (c candidates) Less(i, j int) bool {
+	if c[i].weight != c[j].weight {
+		return c[i].weight > c[j].weight
+	}
+	if c[i].entry != c[j].entry {
+		return c[i].entry
+	}
+	return c[i].text < c[j].text
+}
+
+func (c candidates) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+
+func (c *candidates) Push(x interface{}) { *c = append(*c, x.(candidate)) }
+
+func (c *candidates) Pop() interface{} {
+	old := *c
+	x := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return x
+}
+
+// entry is an entry of a bounded tree, in the heap of lowest weighted ones
+type entry struct {
+	text   string
+	weight int
+	index  int
+}
+
+type entries []*entry
+
+func (e entries) Len() int { return len(e) }
+
+func (e entries) Less(i, j int) bool { return e[i].weight < e[j].weight }
+
+func (e entries) Swap(i, j int) {
+	e[i], e[j] = e[j], e[i]
+	e[i].index, e[j].index = i, j
+}
+
+func (e *entries) Push(x interface{}) {
+	x.(*entry).index = len(*e)
+	*e = append(*e, x.(*entry))
+}
+
+func (e *entries) Pop() interface{} {
+	old := *e
+	x := old[len(old)-1]
+	*e = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,64 @@
+package suggest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	trie := NewTrie()
+	trie.Add("park", 3)
+	trie.Add("parking", 5)
+	trie.Add("pardon", 1)
+	trie.Add("party", 3)
+	trie.Add("teton", 9)
+	trie.Add("par", 2)
+
+	if trie.Len() != 6 {
+		t.Errorf("expected 6 entries, got: %d", trie.Len())
+	}
+
+	want := []Completion{{"parking", 5}, {"park", 3}, {"party", 3}, {"par", 2}}
+	if got := trie.Complete("par", 4); !reflect.DeepEqual(want, got) {
+		t.Errorf("expected completions ordered by weight, got: %v", got)
+	}
+
+	trie.Add("pardon", 10)
+	if got := trie.Complete("pa", 1); len(got) != 1 || got[0] != (Completion{"pardon", 11}) {
+		t.Errorf("expected added weight to promote the entry, got: %v", got)
+	}
+
+	if got := trie.Complete("x", 5); len(got) != 0 {
+		t.Errorf("expected no completions for unknown prefix, got: %v", got)
+	}
+}
+
+func TestBoundedTrie(t *testing.T) {
+	trie := NewBoundedTrie(3)
+	trie.Add("park", 3)
+	trie.Add("parking", 5)
+	trie.Add("pardon", 1)
+	trie.Add("party", 2)
+
+	// the lowest weighted entry makes room for another one
+	if trie.Len() != 3 {
+		t.Errorf("expected 3 entries, got: %d", trie.Len())
+	}
+	want := []Completion{{"parking", 5}, {"park", 3}, {"party", 2}}
+	if got := trie.Complete("par", 5); !reflect.DeepEqual(want, got) {
+		t.Errorf("expected lowest weighted entry to be evicted, got: %v", got)
+	}
+
+	trie.Add("party", 5)
+	trie.Add("teton", 1)
+	want = []Completion{{"party", 7}, {"parking", 5}}
+	if got := trie.Complete("par", 5); !reflect.DeepEqual(want, got) {
+		t.Errorf("expected added weight to keep the entry, got: %v", got)
+	}
+	if got := trie.Complete("parki", 1); len(got) != 1 || got[0] != (Completion{"parking", 5}) {
+		t.Errorf("expected entries under evicted ones to be kept, got: %v", got)
+	}
+	if got := trie.Complete("te", 1); len(got) != 1 || got[0] != (Completion{"teton", 1}) {
+		t.Errorf("expected new entry, got: %v", got)
+	}
+}